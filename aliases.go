@@ -0,0 +1,158 @@
+// Copyright © 2026 Trevor N. Suarez (Rican7)
+
+package lieut
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// commandAlias is a user-defined alias of a command plus arguments.
+type commandAlias struct {
+	name      string
+	expansion string
+
+	arguments []string
+}
+
+// SetAlias sets an alias with the given name, which expands to the given
+// command line (such as "deploy --env prod --confirm") when it's run.
+//
+// The expansion is split into arguments in the style of a POSIX shell, and may
+// refer to other aliases. Any arguments given after the alias are appended to
+// its expansion.
+//
+// It returns an error if the name is empty or is the name of a command, or if
+// the expansion is empty or invalid.
+func (a *MultiCommandApp) SetAlias(name string, expansion string) error {
+	if name == "" || strings.ContainsAny(name, " \t") || isFlagArgument(name) {
+		return fmt.Errorf("invalid alias name '%s'", name)
+	}
+
+	if _, hasCommand := a.commands[name]; hasCommand {
+		return fmt.Errorf("alias '%s' conflicts with a command", name)
+	}
+
+	expansion = strings.TrimSpace(expansion)
+
+	arguments, err := tokenizeArguments(expansion)
+	if err != nil {
+		return fmt.Errorf("invalid expansion of alias '%s': %w", name, err)
+	}
+
+	if len(arguments) == 0 {
+		return fmt.Errorf("empty expansion of alias '%s'", name)
+	}
+
+	if a.aliases == nil {
+		a.aliases = make(map[string]commandAlias)
+	}
+
+	if _, hasAlias := a.aliases[name]; !hasAlias {
+		a.aliasNames = append(a.aliasNames, name)
+	}
+
+	a.aliases[name] = commandAlias{name: name, expansion: expansion, arguments: arguments}
+
+	return nil
+}
+
+// LoadAliases loads aliases from the configuration file at the given path.
+//
+// Each line of the file defines an alias in the form `name = expansion`, as
+// described by SetAlias. Blank lines, and lines starting with `#`, are ignored.
+//
+// It returns an error if the file can't be read (which satisfies errors.Is for
+// os.ErrNotExist if the file doesn't exist), or if any alias is invalid.
+func (a *MultiCommandApp) LoadAliases(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("unable to load aliases: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		name, expansion, ok := strings.Cut(line, "=")
+		if !ok {
+			return fmt.Errorf("invalid alias at %s:%d: expected 'name = expansion'", path, lineNumber)
+		}
+
+		if err := a.SetAlias(strings.TrimSpace(name), expansion); err != nil {
+			return fmt.Errorf("invalid alias at %s:%d: %w", path, lineNumber, err)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("unable to load aliases: %w", err)
+	}
+
+	return nil
+}
+
+// expandAliases expands the alias at the start of the given arguments (if
+// any), recursively.
+//
+// It returns an error if the aliases refer to each other in a loop.
+func (a *MultiCommandApp) expandAliases(arguments []string) ([]string, error) {
+	var seen []string
+
+	for len(arguments) > 0 {
+		name := arguments[0]
+
+		// Commands take precedence over aliases
+		if _, hasCommand := a.commands[name]; hasCommand {
+			break
+		}
+
+		alias, hasAlias := a.aliases[name]
+		if !hasAlias {
+			break
+		}
+
+		for _, seenName := range seen {
+			if seenName == name {
+				return nil, fmt.Errorf("alias loop detected: %s", strings.Join(append(seen, name), " -> "))
+			}
+		}
+
+		seen = append(seen, name)
+
+		arguments = append(append([]string(nil), alias.arguments...), arguments[1:]...)
+	}
+
+	return arguments, nil
+}
+
+func (a *MultiCommandApp) printAliases() {
+	var visible []commandAlias
+	for _, name := range a.aliasNames {
+		if _, hasCommand := a.commands[name]; !hasCommand {
+			visible = append(visible, a.aliases[name])
+		}
+	}
+
+	if len(visible) == 0 {
+		return
+	}
+
+	maxNameLength := 0
+	for _, alias := range visible {
+		if len(alias.name) > maxNameLength {
+			maxNameLength = len(alias.name)
+		}
+	}
+
+	fmt.Fprintf(a.errOut, "\nAliases:\n\n")
+
+	for _, alias := range visible {
+		fmt.Fprintf(a.errOut, "\t%-[1]*s\t= %s\n", maxNameLength, alias.name, alias.expansion)
+	}
+}
@@ -0,0 +1,203 @@
+package lieut
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMultiCommandApp_SetAlias(t *testing.T) {
+	app := NewMultiCommandApp(testAppInfo, nil, io.Discard, io.Discard)
+	_ = app.SetCommand(CommandInfo{Name: "deploy"}, testNoOpExecutor, nil)
+
+	for testName, testData := range map[string]struct {
+		name      string
+		expansion string
+
+		wantErr bool
+	}{
+		"valid": {
+			name:      "ship",
+			expansion: "deploy --env prod",
+		},
+		"empty name": {
+			name:      "",
+			expansion: "deploy",
+
+			wantErr: true,
+		},
+		"flag name": {
+			name:      "-ship",
+			expansion: "deploy",
+
+			wantErr: true,
+		},
+		"command name": {
+			name:      "deploy",
+			expansion: "deploy --env prod",
+
+			wantErr: true,
+		},
+		"empty expansion": {
+			name:      "ship",
+			expansion: "  # nothing",
+
+			wantErr: true,
+		},
+		"invalid expansion": {
+			name:      "ship",
+			expansion: "deploy 'oops",
+
+			wantErr: true,
+		},
+	} {
+		t.Run(testName, func(t *testing.T) {
+			err := app.SetAlias(testData.name, testData.expansion)
+
+			if (err != nil) != testData.wantErr {
+				t.Errorf("app.SetAlias returned error %v, want error %t", err, testData.wantErr)
+			}
+		})
+	}
+}
+
+func TestMultiCommandApp_Run_Aliases(t *testing.T) {
+	var capturedArgs []string
+	executor := func(ctx context.Context, arguments []string) error {
+		capturedArgs = arguments
+		return nil
+	}
+
+	var errOut bytes.Buffer
+
+	app := NewMultiCommandApp(testAppInfo, nil, io.Discard, &errOut)
+	_ = app.SetCommand(CommandInfo{Name: "deploy"}, executor, nil)
+
+	for name, expansion := range map[string]string{
+		"ship":      "deploy 'first arg'",
+		"ship-prod": "ship prod",
+		"loop-a":    "loop-b --flag",
+		"loop-b":    "loop-a",
+	} {
+		if err := app.SetAlias(name, expansion); err != nil {
+			t.Fatalf("app.SetAlias returned error: %v", err)
+		}
+	}
+
+	for testName, testData := range map[string]struct {
+		arguments []string
+
+		wantExitCode int
+		wantArgs     []string
+		wantErrOut   string
+	}{
+		"alias": {
+			arguments: []string{"ship", "extra"},
+
+			wantArgs: []string{"first arg", "extra"},
+		},
+		"nested alias": {
+			arguments: []string{"ship-prod", "extra"},
+
+			wantArgs: []string{"first arg", "prod", "extra"},
+		},
+		"loop": {
+			arguments: []string{"loop-a"},
+
+			wantExitCode: ExitCodeUsageError,
+			wantErrOut:   "Error: alias loop detected: loop-a -> loop-b -> loop-a\n",
+		},
+		"alias as an argument": {
+			arguments: []string{"deploy", "ship"},
+
+			wantArgs: []string{"ship"},
+		},
+	} {
+		t.Run(testName, func(t *testing.T) {
+			capturedArgs = nil
+			errOut.Reset()
+
+			if exitCode := app.Run(context.TODO(), testData.arguments); exitCode != testData.wantExitCode {
+				t.Errorf("app.Run gave %v, wanted %v", exitCode, testData.wantExitCode)
+			}
+
+			if !reflect.DeepEqual(capturedArgs, testData.wantArgs) {
+				t.Errorf("app.Run executor gave %q, wanted %q", capturedArgs, testData.wantArgs)
+			}
+
+			if !strings.HasPrefix(errOut.String(), testData.wantErrOut) {
+				t.Errorf("app.Run gave errOut %q, wanted prefix %q", errOut.String(), testData.wantErrOut)
+			}
+		})
+	}
+}
+
+func TestMultiCommandApp_LoadAliases(t *testing.T) {
+	dir := t.TempDir()
+
+	writeFile := func(name string, contents string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+			t.Fatalf("os.WriteFile returned error: %v", err)
+		}
+
+		return path
+	}
+
+	newApp := func(errOut io.Writer) *MultiCommandApp {
+		app := NewMultiCommandApp(testAppInfo, nil, io.Discard, errOut)
+		_ = app.SetCommand(CommandInfo{Name: "deploy", Summary: "Deploy it"}, testNoOpExecutor, nil)
+
+		return app
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		var errOut bytes.Buffer
+
+		app := newApp(&errOut)
+
+		path := writeFile("valid", "# Deployment aliases\n\nship = deploy\ndeploy-prod = deploy --env prod --confirm\n")
+		if err := app.LoadAliases(path); err != nil {
+			t.Fatalf("app.LoadAliases returned error: %v", err)
+		}
+
+		app.PrintHelp("")
+
+		want := "\nCommands:\n\n" +
+			"\tdeploy\tDeploy it\n" +
+			"\nAliases:\n\n" +
+			"\tship       \t= deploy\n" +
+			"\tdeploy-prod\t= deploy --env prod --confirm\n" +
+			"\nOptions:"
+
+		if !strings.Contains(errOut.String(), want) {
+			t.Errorf("app.PrintHelp gave %q, wanted it to contain %q", errOut.String(), want)
+		}
+
+		if got, want := app.Complete("s"), []string{"ship"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("app.Complete gave %q, wanted %q", got, want)
+		}
+	})
+
+	t.Run("invalid line", func(t *testing.T) {
+		path := writeFile("invalid", "ship = deploy\nnonsense\n")
+
+		err := newApp(io.Discard).LoadAliases(path)
+		if want := "invalid alias at " + path + ":2"; err == nil || !strings.HasPrefix(err.Error(), want) {
+			t.Errorf("app.LoadAliases returned error %v, wanted prefix %q", err, want)
+		}
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		err := newApp(io.Discard).LoadAliases(filepath.Join(dir, "missing"))
+		if !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("app.LoadAliases returned error %v, wanted %v", err, os.ErrNotExist)
+		}
+	})
+}
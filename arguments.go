@@ -0,0 +1,160 @@
+// Copyright © 2026 Trevor N. Suarez (Rican7)
+
+package lieut
+
+import "strings"
+
+// argumentTerminator is the argument that terminates flag parsing, after which
+// all arguments are treated as positional arguments.
+const argumentTerminator = "--"
+
+// intersperseArguments reorders the given arguments so that all of the flags
+// (and their values) come before the positional arguments, which are then
+// separated from the flags by a terminator.
+//
+// The given flags are used to know which flags take a value, so that a flag's
+// value isn't mistaken for a positional argument.
+func intersperseArguments(flags lookupFlagger, arguments []string) []string {
+	var flagArguments, positionalArguments []string
+
+	for i := 0; i < len(arguments); i++ {
+		argument := arguments[i]
+
+		if argument == argumentTerminator {
+			positionalArguments = append(positionalArguments, arguments[i+1:]...)
+			break
+		}
+
+		if !isFlagArgument(argument) || argument == "-" {
+			positionalArguments = append(positionalArguments, argument)
+			continue
+		}
+
+		flagArguments = append(flagArguments, argument)
+
+		name, _, hasValue := strings.Cut(strings.TrimLeft(argument, "-"), "=")
+		if !hasValue && flagTakesValue(flags, name) && i+1 < len(arguments) {
+			flagArguments = append(flagArguments, arguments[i+1])
+			i++
+		}
+	}
+
+	if len(positionalArguments) == 0 {
+		return flagArguments
+	}
+
+	return append(append(flagArguments, argumentTerminator), positionalArguments...)
+}
+
+// flagTakesValue returns whether the named flag requires a value, rather than
+// being a boolean flag that can be provided alone.
+//
+// Undefined flags are reported as not taking a value, leaving them to be
+// reported as undefined when parsed.
+func flagTakesValue(flags lookupFlagger, name string) bool {
+	f := flags.Lookup(name)
+	if f == nil {
+		return false
+	}
+
+	boolValue, isBool := f.Value.(interface{ IsBoolFlag() bool })
+
+	return !isBool || !boolValue.IsBoolFlag()
+}
+
+// stdFlags returns the given flags as standard library compatible flags, if
+// they are.
+func stdFlags(flags Flags) (lookupFlagger, bool) {
+	flags = unwrapFlags(flags)
+
+	lookup, ok := flags.(lookupFlagger)
+
+	return lookup, ok
+}
+
+// normalizeGNUArguments normalizes GNU-style flag arguments into arguments that
+// the standard library's flag package understands.
+//
+// Bundled single-character flags are expanded (`-vvf file` becomes `-v -v -f
+// file`), and values attached to single-character flags are separated (`-ofile`
+// and `-o=file` become `-o file`). Arguments that name a defined flag (such as
+// `-verbose`) are left as-is, for compatibility.
+//
+// Normalization stops at the first positional argument, unless interspersed,
+// as further arguments wouldn't otherwise be parsed as flags.
+func normalizeGNUArguments(flags lookupFlagger, arguments []string, interspersed bool) []string {
+	normalized := make([]string, 0, len(arguments))
+
+	for i := 0; i < len(arguments); i++ {
+		argument := arguments[i]
+
+		if argument == argumentTerminator || (!interspersed && !isFlagArgument(argument)) {
+			normalized = append(normalized, arguments[i:]...)
+			break
+		}
+
+		if !isFlagArgument(argument) || argument == "-" {
+			normalized = append(normalized, argument)
+			continue
+		}
+
+		name, _, hasValue := strings.Cut(strings.TrimLeft(argument, "-"), "=")
+
+		// Leave long flags, and those that name a defined flag, as they are
+		if strings.HasPrefix(argument, "--") || flags.Lookup(name) != nil {
+			normalized = append(normalized, argument)
+
+			if !hasValue && flagTakesValue(flags, name) && i+1 < len(arguments) {
+				normalized = append(normalized, arguments[i+1])
+				i++
+			}
+			continue
+		}
+
+		expanded, needsValue := expandBundledFlags(flags, argument[1:])
+		if expanded == nil {
+			// Leave undefined flags for the flag parser to report
+			normalized = append(normalized, argument)
+			continue
+		}
+
+		normalized = append(normalized, expanded...)
+
+		if needsValue && i+1 < len(arguments) {
+			normalized = append(normalized, arguments[i+1])
+			i++
+		}
+	}
+
+	return normalized
+}
+
+// expandBundledFlags expands the given bundle of single-character flags into
+// separate flag arguments, returning nil if any of the flags aren't defined.
+//
+// It also returns whether the last flag in the bundle still needs a value,
+// which should be taken from the next argument.
+func expandBundledFlags(flags lookupFlagger, bundle string) ([]string, bool) {
+	var expanded []string
+
+	for i, char := range bundle {
+		name := string(char)
+
+		if flags.Lookup(name) == nil {
+			return nil, false
+		}
+
+		expanded = append(expanded, "-"+name)
+
+		if flagTakesValue(flags, name) {
+			value := strings.TrimPrefix(bundle[i+len(name):], "=")
+			if value == "" {
+				return expanded, true
+			}
+
+			return append(expanded, value), false
+		}
+	}
+
+	return expanded, false
+}
@@ -0,0 +1,141 @@
+package lieut
+
+import (
+	"flag"
+	"reflect"
+	"testing"
+)
+
+func TestIntersperseArguments(t *testing.T) {
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags.Bool("force", false, "Force it")
+	flags.String("env", "", "The environment")
+
+	for testName, testData := range map[string]struct {
+		arguments []string
+		want      []string
+	}{
+		"empty": {
+			arguments: []string{},
+			want:      nil,
+		},
+		"only flags": {
+			arguments: []string{"--force", "--env", "prod"},
+			want:      []string{"--force", "--env", "prod"},
+		},
+		"only positional arguments": {
+			arguments: []string{"prod", "now"},
+			want:      []string{"--", "prod", "now"},
+		},
+		"interspersed": {
+			arguments: []string{"deploy", "--force", "prod", "-env", "staging", "now"},
+			want:      []string{"--force", "-env", "staging", "--", "deploy", "prod", "now"},
+		},
+		"attached value": {
+			arguments: []string{"prod", "--env=staging"},
+			want:      []string{"--env=staging", "--", "prod"},
+		},
+		"explicit bool value": {
+			arguments: []string{"prod", "--force=false"},
+			want:      []string{"--force=false", "--", "prod"},
+		},
+		"terminator": {
+			arguments: []string{"prod", "--", "--force", "-env"},
+			want:      []string{"--", "prod", "--force", "-env"},
+		},
+		"stdin argument": {
+			arguments: []string{"-", "--force"},
+			want:      []string{"--force", "--", "-"},
+		},
+		"undefined flag": {
+			arguments: []string{"prod", "--undefined", "value"},
+			want:      []string{"--undefined", "--", "prod", "value"},
+		},
+		"missing value": {
+			arguments: []string{"prod", "--env"},
+			want:      []string{"--env", "--", "prod"},
+		},
+	} {
+		t.Run(testName, func(t *testing.T) {
+			got := intersperseArguments(flags, testData.arguments)
+
+			if !reflect.DeepEqual(got, testData.want) {
+				t.Errorf("intersperseArguments gave %q, want %q", got, testData.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeGNUArguments(t *testing.T) {
+	var verbosity int
+
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags.Var(CountValue(&verbosity), "verbose", "Increase verbosity")
+	flags.Bool("force", false, "Force it")
+	flags.String("output", "", "The output file")
+
+	_ = SetFlagShorthand(flags, "verbose", "v")
+	_ = SetFlagShorthand(flags, "force", "f")
+	_ = SetFlagShorthand(flags, "output", "o")
+
+	for testName, testData := range map[string]struct {
+		arguments    []string
+		interspersed bool
+		want         []string
+	}{
+		"bundled bool flags": {
+			arguments: []string{"-vvf"},
+			want:      []string{"-v", "-v", "-f"},
+		},
+		"bundled flags with separate value": {
+			arguments: []string{"-vo", "file", "arg"},
+			want:      []string{"-v", "-o", "file", "arg"},
+		},
+		"attached value": {
+			arguments: []string{"-ofile"},
+			want:      []string{"-o", "file"},
+		},
+		"attached value with equals": {
+			arguments: []string{"-o=file"},
+			want:      []string{"-o=file"},
+		},
+		"bundled flags with attached value": {
+			arguments: []string{"-vfo=file"},
+			want:      []string{"-v", "-f", "-o", "file"},
+		},
+		"long flags": {
+			arguments: []string{"--verbose", "--output", "-vf"},
+			want:      []string{"--verbose", "--output", "-vf"},
+		},
+		"single-dash long flags": {
+			arguments: []string{"-verbose", "-output", "file"},
+			want:      []string{"-verbose", "-output", "file"},
+		},
+		"undefined bundle": {
+			arguments: []string{"-vx"},
+			want:      []string{"-vx"},
+		},
+		"stops at positional argument": {
+			arguments: []string{"-vv", "arg", "-vf"},
+			want:      []string{"-v", "-v", "arg", "-vf"},
+		},
+		"continues past positional argument when interspersed": {
+			arguments:    []string{"-vv", "arg", "-vf"},
+			interspersed: true,
+			want:         []string{"-v", "-v", "arg", "-v", "-f"},
+		},
+		"terminator": {
+			arguments:    []string{"-vv", "--", "-vf"},
+			interspersed: true,
+			want:         []string{"-v", "-v", "--", "-vf"},
+		},
+	} {
+		t.Run(testName, func(t *testing.T) {
+			got := normalizeGNUArguments(flags, testData.arguments, testData.interspersed)
+
+			if !reflect.DeepEqual(got, testData.want) {
+				t.Errorf("normalizeGNUArguments gave %q, want %q", got, testData.want)
+			}
+		})
+	}
+}
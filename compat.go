@@ -0,0 +1,262 @@
+// Copyright © 2026 Trevor N. Suarez (Rican7)
+
+package lieut
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Severity describes how impactful a change between two app descriptions is.
+type Severity int
+
+// Severities, from least to most impactful.
+const (
+	// SeverityInfo is the severity of a change that is backwards compatible,
+	// such as adding a new command or flag.
+	SeverityInfo Severity = iota
+
+	// SeverityWarning is the severity of a change that is backwards compatible,
+	// but that may still alter the behavior of existing invocations, such as
+	// changing the default value of a flag.
+	SeverityWarning
+
+	// SeverityBreaking is the severity of a change that can break existing
+	// invocations, such as removing a command or flag.
+	SeverityBreaking
+)
+
+// String returns the name of the severity.
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityBreaking:
+		return "breaking"
+	default:
+		return fmt.Sprintf("severity(%d)", int(s))
+	}
+}
+
+// Change describes a single difference between two app descriptions.
+type Change struct {
+	Severity Severity
+
+	// Command is the name of the command that the change applies to, or empty
+	// if the change applies to the app itself (or its global flags).
+	Command string
+
+	// Flag is the name of the flag that the change applies to, or empty if the
+	// change doesn't apply to a flag.
+	Flag string
+
+	Message string
+}
+
+// String returns a human-readable representation of the change.
+func (c Change) String() string {
+	var sb strings.Builder
+
+	sb.WriteString(c.Severity.String())
+	sb.WriteString(": ")
+
+	if c.Command != "" {
+		fmt.Fprintf(&sb, "command '%s': ", c.Command)
+	}
+
+	sb.WriteString(c.Message)
+
+	return sb.String()
+}
+
+// Changes is a list of changes between two app descriptions.
+type Changes []Change
+
+// AtLeast returns the changes with a severity greater than or equal to the
+// given severity.
+func (c Changes) AtLeast(severity Severity) Changes {
+	var filtered Changes
+
+	for _, change := range c {
+		if change.Severity >= severity {
+			filtered = append(filtered, change)
+		}
+	}
+
+	return filtered
+}
+
+// HasBreaking returns whether any of the changes are breaking.
+func (c Changes) HasBreaking() bool {
+	return len(c.AtLeast(SeverityBreaking)) > 0
+}
+
+// CompareDescriptions compares two descriptions of an app and returns the
+// changes required to go from the old description to the updated one.
+//
+// Commands are compared by name. Flags are compared by name within their
+// command, and a removed flag is reported as renamed if a flag with the same
+// type and default, and the same shorthand or usage, was added in its place.
+func CompareDescriptions(old AppDescription, updated AppDescription) Changes {
+	changes := compareFlags("", old.Flags, updated.Flags)
+
+	newCommands := make(map[string]CommandDescription, len(updated.Commands))
+	for _, command := range updated.Commands {
+		newCommands[command.Name] = command
+	}
+
+	oldCommands := make(map[string]CommandDescription, len(old.Commands))
+	for _, oldCommand := range old.Commands {
+		oldCommands[oldCommand.Name] = oldCommand
+
+		newCommand, hasCommand := newCommands[oldCommand.Name]
+		if !hasCommand {
+			changes = append(changes, Change{
+				Severity: SeverityBreaking,
+				Command:  oldCommand.Name,
+				Message:  "command was removed",
+			})
+			continue
+		}
+
+		changes = append(changes, compareFlags(oldCommand.Name, oldCommand.Flags, newCommand.Flags)...)
+	}
+
+	for _, newCommand := range updated.Commands {
+		if _, hasCommand := oldCommands[newCommand.Name]; !hasCommand {
+			changes = append(changes, Change{
+				Severity: SeverityInfo,
+				Command:  newCommand.Name,
+				Message:  "command was added",
+			})
+		}
+	}
+
+	return changes
+}
+
+func compareFlags(commandName string, oldFlags []FlagDescription, newFlags []FlagDescription) Changes {
+	var changes Changes
+
+	change := func(severity Severity, flagName string, format string, args ...any) {
+		changes = append(changes, Change{
+			Severity: severity,
+			Command:  commandName,
+			Flag:     flagName,
+			Message:  fmt.Sprintf(format, args...),
+		})
+	}
+
+	oldByName := make(map[string]FlagDescription, len(oldFlags))
+	for _, f := range oldFlags {
+		oldByName[f.Name] = f
+	}
+
+	newByName := make(map[string]FlagDescription, len(newFlags))
+	var added []FlagDescription
+	for _, f := range newFlags {
+		newByName[f.Name] = f
+
+		if _, existed := oldByName[f.Name]; !existed {
+			added = append(added, f)
+		}
+	}
+
+	// Keep track of added flags that were matched as renames, so that they
+	// aren't reported twice
+	renamed := make(map[string]bool)
+
+	for _, oldFlag := range oldFlags {
+		newFlag, hasFlag := newByName[oldFlag.Name]
+		if !hasFlag {
+			if replacement, ok := findRenamedFlag(oldFlag, added, renamed); ok {
+				renamed[replacement.Name] = true
+				change(SeverityBreaking, oldFlag.Name, "flag '%s' was renamed to '%s'", oldFlag.Name, replacement.Name)
+				continue
+			}
+
+			change(SeverityBreaking, oldFlag.Name, "flag '%s' was removed", oldFlag.Name)
+			continue
+		}
+
+		if oldFlag.Type != newFlag.Type {
+			change(
+				SeverityBreaking,
+				oldFlag.Name,
+				"flag '%s' changed type from '%s' to '%s'",
+				oldFlag.Name,
+				oldFlag.Type,
+				newFlag.Type,
+			)
+		}
+
+		if oldFlag.Shorthand != "" && oldFlag.Shorthand != newFlag.Shorthand {
+			change(SeverityBreaking, oldFlag.Name, "flag '%s' lost shorthand '%s'", oldFlag.Name, oldFlag.Shorthand)
+		}
+
+		if oldFlag.Negatable && !newFlag.Negatable {
+			change(SeverityBreaking, oldFlag.Name, "flag '%s' is no longer negatable", oldFlag.Name)
+		}
+
+		if !oldFlag.Required && newFlag.Required {
+			change(SeverityBreaking, oldFlag.Name, "flag '%s' is now required", oldFlag.Name)
+		}
+
+		if oldFlag.Default != newFlag.Default {
+			change(
+				SeverityWarning,
+				oldFlag.Name,
+				"flag '%s' changed default from %q to %q",
+				oldFlag.Name,
+				oldFlag.Default,
+				newFlag.Default,
+			)
+		}
+	}
+
+	for _, newFlag := range added {
+		if renamed[newFlag.Name] {
+			continue
+		}
+
+		if newFlag.Required {
+			change(SeverityBreaking, newFlag.Name, "required flag '%s' was added", newFlag.Name)
+			continue
+		}
+
+		change(SeverityInfo, newFlag.Name, "flag '%s' was added", newFlag.Name)
+	}
+
+	return changes
+}
+
+// findRenamedFlag finds a flag in the given added flags that's likely to be a
+// renamed version of the given old flag, skipping those already matched.
+func findRenamedFlag(
+	oldFlag FlagDescription,
+	added []FlagDescription,
+	renamed map[string]bool,
+) (FlagDescription, bool) {
+	for _, newFlag := range added {
+		if renamed[newFlag.Name] {
+			continue
+		}
+
+		// Unrelated flags can share a shorthand or usage, but are unlikely to
+		// also share a type and default
+		if oldFlag.Type != newFlag.Type || oldFlag.Default != newFlag.Default {
+			continue
+		}
+
+		sameShorthand := oldFlag.Shorthand != "" && oldFlag.Shorthand == newFlag.Shorthand
+		sameUsage := oldFlag.Usage != "" && oldFlag.Usage == newFlag.Usage
+
+		if sameShorthand || sameUsage {
+			return newFlag, true
+		}
+	}
+
+	return FlagDescription{}, false
+}
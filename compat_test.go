@@ -0,0 +1,244 @@
+package lieut
+
+import (
+	"flag"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestCompareDescriptions(t *testing.T) {
+	base := AppDescription{
+		Name: "test",
+		Flags: []FlagDescription{
+			{Name: "verbose", Type: "bool", Usage: "Enable verbose output", Negatable: true},
+		},
+		Commands: []CommandDescription{
+			{
+				Name: "deploy",
+				Flags: []FlagDescription{
+					{Name: "env", Shorthand: "e", Type: "string", Usage: "The environment", Default: "dev"},
+					{Name: "timeout", Type: "duration", Usage: "The timeout", Default: "1m0s"},
+				},
+			},
+			{Name: "status"},
+		},
+	}
+
+	for testName, testData := range map[string]struct {
+		modify func(d *AppDescription)
+		want   Changes
+	}{
+		"no changes": {
+			modify: func(d *AppDescription) {},
+			want:   nil,
+		},
+		"removed command": {
+			modify: func(d *AppDescription) {
+				d.Commands = d.Commands[:1]
+			},
+			want: Changes{
+				{Severity: SeverityBreaking, Command: "status", Message: "command was removed"},
+			},
+		},
+		"added command": {
+			modify: func(d *AppDescription) {
+				d.Commands = append(d.Commands, CommandDescription{Name: "rollback"})
+			},
+			want: Changes{
+				{Severity: SeverityInfo, Command: "rollback", Message: "command was added"},
+			},
+		},
+		"removed global flag": {
+			modify: func(d *AppDescription) {
+				d.Flags = nil
+			},
+			want: Changes{
+				{Severity: SeverityBreaking, Flag: "verbose", Message: "flag 'verbose' was removed"},
+			},
+		},
+		"renamed flag": {
+			modify: func(d *AppDescription) {
+				d.Commands[0].Flags[0].Name = "environment"
+			},
+			want: Changes{
+				{
+					Severity: SeverityBreaking,
+					Command:  "deploy",
+					Flag:     "env",
+					Message:  "flag 'env' was renamed to 'environment'",
+				},
+			},
+		},
+		"unrelated flag with the same usage": {
+			modify: func(d *AppDescription) {
+				d.Commands[0].Flags[1] = FlagDescription{Name: "deadline", Type: "string", Usage: "The timeout"}
+			},
+			want: Changes{
+				{Severity: SeverityBreaking, Command: "deploy", Flag: "timeout", Message: "flag 'timeout' was removed"},
+				{Severity: SeverityInfo, Command: "deploy", Flag: "deadline", Message: "flag 'deadline' was added"},
+			},
+		},
+		"changed flag type": {
+			modify: func(d *AppDescription) {
+				d.Commands[0].Flags[1].Type = "int"
+			},
+			want: Changes{
+				{
+					Severity: SeverityBreaking,
+					Command:  "deploy",
+					Flag:     "timeout",
+					Message:  "flag 'timeout' changed type from 'duration' to 'int'",
+				},
+			},
+		},
+		"removed shorthand": {
+			modify: func(d *AppDescription) {
+				d.Commands[0].Flags[0].Shorthand = ""
+			},
+			want: Changes{
+				{Severity: SeverityBreaking, Command: "deploy", Flag: "env", Message: "flag 'env' lost shorthand 'e'"},
+			},
+		},
+		"no longer negatable flag": {
+			modify: func(d *AppDescription) {
+				d.Flags[0].Negatable = false
+			},
+			want: Changes{
+				{Severity: SeverityBreaking, Flag: "verbose", Message: "flag 'verbose' is no longer negatable"},
+			},
+		},
+		"newly required flag": {
+			modify: func(d *AppDescription) {
+				d.Commands[0].Flags[0].Required = true
+			},
+			want: Changes{
+				{Severity: SeverityBreaking, Command: "deploy", Flag: "env", Message: "flag 'env' is now required"},
+			},
+		},
+		"added required flag": {
+			modify: func(d *AppDescription) {
+				d.Commands[1].Flags = []FlagDescription{{Name: "all", Required: true}}
+			},
+			want: Changes{
+				{Severity: SeverityBreaking, Command: "status", Flag: "all", Message: "required flag 'all' was added"},
+			},
+		},
+		"added optional flag": {
+			modify: func(d *AppDescription) {
+				d.Commands[1].Flags = []FlagDescription{{Name: "all"}}
+			},
+			want: Changes{
+				{Severity: SeverityInfo, Command: "status", Flag: "all", Message: "flag 'all' was added"},
+			},
+		},
+		"changed default": {
+			modify: func(d *AppDescription) {
+				d.Commands[0].Flags[0].Default = "prod"
+			},
+			want: Changes{
+				{
+					Severity: SeverityWarning,
+					Command:  "deploy",
+					Flag:     "env",
+					Message:  `flag 'env' changed default from "dev" to "prod"`,
+				},
+			},
+		},
+	} {
+		t.Run(testName, func(t *testing.T) {
+			updated := copyDescription(base)
+			testData.modify(&updated)
+
+			got := CompareDescriptions(base, updated)
+
+			if !reflect.DeepEqual(got, testData.want) {
+				t.Errorf("CompareDescriptions gave %v, want %v", got, testData.want)
+			}
+		})
+	}
+}
+
+func TestCompareDescriptions_Described(t *testing.T) {
+	describe := func(required bool) AppDescription {
+		flagSet := flag.NewFlagSet(testAppInfo.Name, flag.ContinueOnError)
+		flagSet.String("token", "", "An API token")
+
+		if required {
+			_ = MarkFlagRequired(flagSet, "token")
+		}
+
+		return NewSingleCommandApp(testAppInfo, testNoOpExecutor, flagSet, io.Discard, io.Discard).Describe()
+	}
+
+	got := CompareDescriptions(describe(false), describe(true))
+	want := Changes{
+		{Severity: SeverityBreaking, Flag: "token", Message: "flag 'token' is now required"},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CompareDescriptions gave %v, want %v", got, want)
+	}
+}
+
+func TestChanges_AtLeast(t *testing.T) {
+	changes := Changes{
+		{Severity: SeverityInfo, Message: "info"},
+		{Severity: SeverityBreaking, Message: "breaking"},
+		{Severity: SeverityWarning, Message: "warning"},
+	}
+
+	got := changes.AtLeast(SeverityWarning)
+	want := Changes{changes[1], changes[2]}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("changes.AtLeast gave %v, want %v", got, want)
+	}
+
+	if !changes.HasBreaking() {
+		t.Error("changes.HasBreaking returned false, wanted true")
+	}
+
+	if changes[:1].HasBreaking() {
+		t.Error("changes.HasBreaking returned true, wanted false")
+	}
+}
+
+func TestChange_String(t *testing.T) {
+	for testName, testData := range map[string]struct {
+		change Change
+		want   string
+	}{
+		"app": {
+			change: Change{Severity: SeverityBreaking, Flag: "verbose", Message: "flag 'verbose' was removed"},
+			want:   "breaking: flag 'verbose' was removed",
+		},
+		"command": {
+			change: Change{Severity: SeverityInfo, Command: "deploy", Message: "command was added"},
+			want:   "info: command 'deploy': command was added",
+		},
+		"unknown severity": {
+			change: Change{Severity: Severity(9), Message: "something"},
+			want:   "severity(9): something",
+		},
+	} {
+		t.Run(testName, func(t *testing.T) {
+			if got := testData.change.String(); got != testData.want {
+				t.Errorf("change.String gave %q, want %q", got, testData.want)
+			}
+		})
+	}
+}
+
+func copyDescription(d AppDescription) AppDescription {
+	d.Flags = append([]FlagDescription(nil), d.Flags...)
+
+	commands := make([]CommandDescription, len(d.Commands))
+	for i, command := range d.Commands {
+		command.Flags = append([]FlagDescription(nil), command.Flags...)
+		commands[i] = command
+	}
+	d.Commands = commands
+
+	return d
+}
@@ -0,0 +1,129 @@
+// Copyright © 2026 Trevor N. Suarez (Rican7)
+
+package lieut
+
+import (
+	"context"
+	"io"
+	"os"
+)
+
+type contextKey int
+
+// Context keys, for values provided to an Executor via its context.
+const (
+	flagStatesContextKey contextKey = iota
+	stdoutContextKey
+	stderrContextKey
+	stdinContextKey
+	runMetadataContextKey
+)
+
+// runMetadata describes a single run of an app.
+type runMetadata struct {
+	app         AppInfo
+	command     *CommandInfo // The command being run, for MultiCommandApps
+	commandPath string
+	arguments   []string // The original arguments given to the run
+}
+
+// AppInfoFromContext returns the info of the running app, from the context
+// given to an Executor.
+//
+// It returns false if the context isn't from a run.
+func AppInfoFromContext(ctx context.Context) (AppInfo, bool) {
+	run, ok := ctx.Value(runMetadataContextKey).(runMetadata)
+
+	return run.app, ok
+}
+
+// CommandInfoFromContext returns the info of the running command, from the
+// context given to an Executor of a MultiCommandApp.
+//
+// It returns false if the context isn't from a run of a MultiCommandApp.
+func CommandInfoFromContext(ctx context.Context) (CommandInfo, bool) {
+	run, ok := ctx.Value(runMetadataContextKey).(runMetadata)
+	if !ok || run.command == nil {
+		return CommandInfo{}, false
+	}
+
+	return *run.command, true
+}
+
+// CommandPathFromContext returns the full path of the running command, such as
+// "app deploy", from the context given to an Executor. For a SingleCommandApp,
+// it's just the name of the app.
+//
+// It returns an empty string if the context isn't from a run.
+func CommandPathFromContext(ctx context.Context) string {
+	run, _ := ctx.Value(runMetadataContextKey).(runMetadata)
+
+	return run.commandPath
+}
+
+// ArgumentsFromContext returns the original arguments of the run, before any
+// flags or commands were parsed from them, from the context given to an
+// Executor.
+//
+// It returns nil if the context isn't from a run.
+func ArgumentsFromContext(ctx context.Context) []string {
+	run, _ := ctx.Value(runMetadataContextKey).(runMetadata)
+
+	return append([]string(nil), run.arguments...)
+}
+
+// Stdout returns the app's standard output writer, from the context given to an
+// Executor.
+//
+// It returns os.Stdout if the context isn't from a run.
+func Stdout(ctx context.Context) io.Writer {
+	if out, ok := ctx.Value(stdoutContextKey).(io.Writer); ok {
+		return out
+	}
+
+	return os.Stdout
+}
+
+// Stderr returns the app's error output writer, from the context given to an
+// Executor.
+//
+// It returns os.Stderr if the context isn't from a run.
+func Stderr(ctx context.Context) io.Writer {
+	if errOut, ok := ctx.Value(stderrContextKey).(io.Writer); ok {
+		return errOut
+	}
+
+	return os.Stderr
+}
+
+// Stdin returns the app's input reader (see SetInput), from the context given
+// to an Executor.
+//
+// It returns os.Stdin if the context isn't from a run.
+func Stdin(ctx context.Context) io.Reader {
+	if in, ok := ctx.Value(stdinContextKey).(io.Reader); ok {
+		return in
+	}
+
+	return os.Stdin
+}
+
+// withRunMetadata returns a copy of the given context that holds the given run
+// metadata.
+func withRunMetadata(ctx context.Context, run runMetadata) context.Context {
+	return context.WithValue(ctx, runMetadataContextKey, run)
+}
+
+// withIO returns a copy of the given context that holds the app's I/O.
+func (a *app) withIO(ctx context.Context) context.Context {
+	in := a.in
+	if in == nil {
+		in = os.Stdin
+	}
+
+	ctx = context.WithValue(ctx, stdoutContextKey, a.out)
+	ctx = context.WithValue(ctx, stderrContextKey, a.errOut)
+	ctx = context.WithValue(ctx, stdinContextKey, in)
+
+	return ctx
+}
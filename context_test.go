@@ -0,0 +1,173 @@
+package lieut
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestContextIO_Defaults(t *testing.T) {
+	ctx := context.TODO()
+
+	if got := Stdout(ctx); got != os.Stdout {
+		t.Errorf("Stdout gave %v, want os.Stdout", got)
+	}
+
+	if got := Stderr(ctx); got != os.Stderr {
+		t.Errorf("Stderr gave %v, want os.Stderr", got)
+	}
+
+	if got := Stdin(ctx); got != os.Stdin {
+		t.Errorf("Stdin gave %v, want os.Stdin", got)
+	}
+}
+
+func TestContextIO_Run(t *testing.T) {
+	var out, errOut bytes.Buffer
+	in := strings.NewReader("input")
+
+	executor := func(ctx context.Context, arguments []string) error {
+		input, err := io.ReadAll(Stdin(ctx))
+		if err != nil {
+			return err
+		}
+
+		_, _ = io.WriteString(Stdout(ctx), "out: "+string(input))
+		_, _ = io.WriteString(Stderr(ctx), "errOut: "+string(input))
+
+		return nil
+	}
+
+	app := NewSingleCommandApp(testAppInfo, executor, nil, &out, &errOut)
+	app.SetInput(in)
+
+	if exitCode := app.Run(context.TODO(), []string{"arg"}); exitCode != ExitCodeSuccess {
+		t.Errorf("app.Run gave %v, wanted %v", exitCode, ExitCodeSuccess)
+	}
+
+	if got, want := out.String(), "out: input"; got != want {
+		t.Errorf("app.Run gave out %q, wanted %q", got, want)
+	}
+
+	if got, want := errOut.String(), "errOut: input"; got != want {
+		t.Errorf("app.Run gave errOut %q, wanted %q", got, want)
+	}
+}
+
+func TestContextIO_DefaultInput(t *testing.T) {
+	var stdin io.Reader
+
+	executor := func(ctx context.Context, arguments []string) error {
+		stdin = Stdin(ctx)
+		return nil
+	}
+
+	app := NewSingleCommandApp(testAppInfo, executor, nil, io.Discard, io.Discard)
+	app.Run(context.TODO(), []string{"arg"})
+
+	if stdin != os.Stdin {
+		t.Errorf("app.Run gave stdin %v, wanted os.Stdin", stdin)
+	}
+}
+
+func TestRunMetadata_Defaults(t *testing.T) {
+	ctx := context.TODO()
+
+	if _, ok := AppInfoFromContext(ctx); ok {
+		t.Error("AppInfoFromContext returned true for a context without metadata")
+	}
+
+	if _, ok := CommandInfoFromContext(ctx); ok {
+		t.Error("CommandInfoFromContext returned true for a context without metadata")
+	}
+
+	if got := CommandPathFromContext(ctx); got != "" {
+		t.Errorf("CommandPathFromContext gave %q, want empty", got)
+	}
+
+	if got := ArgumentsFromContext(ctx); got != nil {
+		t.Errorf("ArgumentsFromContext gave %q, want nil", got)
+	}
+}
+
+func TestRunMetadata_SingleCommandApp(t *testing.T) {
+	var appInfo AppInfo
+	var hasAppInfo, hasCommandInfo bool
+	var commandPath string
+	var arguments []string
+
+	executor := func(ctx context.Context, _ []string) error {
+		appInfo, hasAppInfo = AppInfoFromContext(ctx)
+		_, hasCommandInfo = CommandInfoFromContext(ctx)
+		commandPath = CommandPathFromContext(ctx)
+		arguments = ArgumentsFromContext(ctx)
+		return nil
+	}
+
+	app := NewSingleCommandApp(testAppInfo, executor, nil, io.Discard, io.Discard)
+	app.Run(context.TODO(), []string{"-help=false", "arg"})
+
+	if !hasAppInfo || appInfo != testAppInfo {
+		t.Errorf("AppInfoFromContext gave %+v (%v), want %+v", appInfo, hasAppInfo, testAppInfo)
+	}
+
+	if hasCommandInfo {
+		t.Error("CommandInfoFromContext returned true for a SingleCommandApp")
+	}
+
+	if commandPath != testAppInfo.Name {
+		t.Errorf("CommandPathFromContext gave %q, want %q", commandPath, testAppInfo.Name)
+	}
+
+	if want := []string{"-help=false", "arg"}; !reflect.DeepEqual(arguments, want) {
+		t.Errorf("ArgumentsFromContext gave %q, want %q", arguments, want)
+	}
+}
+
+func TestRunMetadata_MultiCommandApp(t *testing.T) {
+	var commandInfo CommandInfo
+	var hasCommandInfo bool
+	var commandPath string
+	var arguments []string
+
+	executor := func(ctx context.Context, _ []string) error {
+		commandInfo, hasCommandInfo = CommandInfoFromContext(ctx)
+		commandPath = CommandPathFromContext(ctx)
+		arguments = ArgumentsFromContext(ctx)
+		return nil
+	}
+
+	info := CommandInfo{Name: "deploy", Summary: "Deploy it", Usage: "<target>"}
+
+	globalFlags := flag.NewFlagSet(testAppInfo.Name, flag.ContinueOnError)
+	globalFlags.Bool("verbose", false, "Enable verbose output")
+
+	app := NewMultiCommandApp(testAppInfo, globalFlags, io.Discard, io.Discard)
+	_ = app.SetCommand(info, executor, nil)
+	app.SetDefaultCommand("deploy")
+
+	app.Run(context.TODO(), []string{"deploy", "prod"})
+
+	if !hasCommandInfo || commandInfo != info {
+		t.Errorf("CommandInfoFromContext gave %+v (%v), want %+v", commandInfo, hasCommandInfo, info)
+	}
+
+	if want := "test deploy"; commandPath != want {
+		t.Errorf("CommandPathFromContext gave %q, want %q", commandPath, want)
+	}
+
+	if want := []string{"deploy", "prod"}; !reflect.DeepEqual(arguments, want) {
+		t.Errorf("ArgumentsFromContext gave %q, want %q", arguments, want)
+	}
+
+	app.Run(context.TODO(), []string{"-verbose"})
+
+	if want := []string{"-verbose"}; !reflect.DeepEqual(arguments, want) {
+		t.Errorf("ArgumentsFromContext gave %q for the default command, want %q", arguments, want)
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright © 2026 Trevor N. Suarez (Rican7)
+
+package lieut
+
+// AppDescription describes the command and flag surface of an app.
+//
+// It's designed to be serializable (as JSON, for example), so that it can be
+// stored and compared against later versions of an app. Hidden commands and
+// flags are included (and marked as such), as they're still part of the app's
+// surface, so generated documentation should omit them as appropriate.
+type AppDescription struct {
+	Name    string `json:"name"`
+	Summary string `json:"summary,omitempty"`
+	Usage   string `json:"usage,omitempty"`
+	Version string `json:"version,omitempty"`
+
+	Flags    []FlagDescription    `json:"flags,omitempty"`
+	Commands []CommandDescription `json:"commands,omitempty"`
+}
+
+// CommandDescription describes the surface of a command.
+//
+// The flags of a command don't include those inherited from the app's
+// global/shared flags, as those are already described by the app.
+type CommandDescription struct {
+	Name    string `json:"name"`
+	Summary string `json:"summary,omitempty"`
+	Usage   string `json:"usage,omitempty"`
+	Hidden  bool   `json:"hidden,omitempty"`
+
+	Flags []FlagDescription `json:"flags,omitempty"`
+}
+
+// FlagDescription describes a flag.
+type FlagDescription struct {
+	Name      string `json:"name"`
+	Shorthand string `json:"shorthand,omitempty"`
+	Type      string `json:"type,omitempty"`
+	Usage     string `json:"usage,omitempty"`
+	Default   string `json:"default,omitempty"`
+	Required  bool   `json:"required,omitempty"`
+	Negatable bool   `json:"negatable,omitempty"`
+	Hidden    bool   `json:"hidden,omitempty"`
+}
+
+// Describe returns a description of the app's command and flag surface.
+func (a *SingleCommandApp) Describe() AppDescription {
+	description := a.describeApp()
+	description.Flags = describeFlags(a.flags)
+
+	return description
+}
+
+// Describe returns a description of the app's command and flag surface.
+func (a *MultiCommandApp) Describe() AppDescription {
+	description := a.describeApp()
+	description.Flags = describeFlags(a.flags)
+
+	for _, name := range a.commandNames {
+		command := a.commands[name]
+
+		description.Commands = append(description.Commands, CommandDescription{
+			Name:    command.info.Name,
+			Summary: command.info.Summary,
+			Usage:   command.info.Usage,
+			Hidden:  command.info.Hidden,
+
+			Flags: describeFlags(command.flags),
+		})
+	}
+
+	return description
+}
+
+func (a *app) describeApp() AppDescription {
+	return AppDescription{
+		Name:    a.info.Name,
+		Summary: a.info.Summary,
+		Usage:   a.info.Usage,
+		Version: a.info.Version,
+	}
+}
+
+func describeFlags(flagSet *flagSet) []FlagDescription {
+	var descriptions []FlagDescription
+
+	visitFlags(flagSet.Flags, func(f flagInfo) {
+		if flagSet.inherited[f.name] {
+			return
+		}
+
+		descriptions = append(descriptions, FlagDescription{
+			Name:      f.name,
+			Shorthand: f.shorthand,
+			Type:      f.typeName,
+			Usage:     f.usage,
+			Default:   f.defValue,
+			Required:  f.required,
+			Negatable: f.negatable,
+			Hidden:    f.hidden,
+		})
+	})
+
+	return descriptions
+}
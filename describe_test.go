@@ -0,0 +1,88 @@
+package lieut
+
+import (
+	"flag"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestSingleCommandApp_Describe(t *testing.T) {
+	flagSet := flag.NewFlagSet(testAppInfo.Name, flag.ContinueOnError)
+	flagSet.String("testflag", "testval", "A test flag")
+	flagSet.String("token", "", "An API token")
+
+	_ = MarkFlagRequired(flagSet, "token")
+
+	app := NewSingleCommandApp(testAppInfo, testNoOpExecutor, flagSet, io.Discard, io.Discard)
+
+	want := AppDescription{
+		Name:    "test",
+		Summary: "A test",
+		Usage:   "testing",
+		Version: "vTest",
+
+		Flags: []FlagDescription{
+			{Name: "help", Usage: "Display the help message", Default: "false"},
+			{Name: "testflag", Type: "string", Usage: "A test flag", Default: "testval"},
+			{Name: "token", Type: "string", Usage: "An API token", Required: true},
+			{Name: "version", Usage: "Display the application version", Default: "false"},
+		},
+	}
+
+	got := app.Describe()
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("app.Describe gave %+v, want %+v", got, want)
+	}
+}
+
+func TestMultiCommandApp_Describe(t *testing.T) {
+	flagSet := flag.NewFlagSet(testAppInfo.Name, flag.ContinueOnError)
+	flagSet.String("testflag", "testval", "A test flag")
+
+	app := NewMultiCommandApp(testAppInfo, flagSet, io.Discard, io.Discard)
+
+	commandFlagSet := flag.NewFlagSet("testcommand", flag.ContinueOnError)
+	commandFlagSet.Int("testcommandflag", 5, "A test command flag")
+
+	err := app.SetCommand(
+		CommandInfo{Name: "testcommand", Summary: "A test command", Usage: "args here..."},
+		testNoOpExecutor,
+		commandFlagSet,
+	)
+	if err != nil {
+		t.Fatalf("SetCommand returned error: %v", err)
+	}
+
+	want := AppDescription{
+		Name:    "test",
+		Summary: "A test",
+		Usage:   "testing",
+		Version: "vTest",
+
+		Flags: []FlagDescription{
+			{Name: "help", Usage: "Display the help message", Default: "false"},
+			{Name: "testflag", Type: "string", Usage: "A test flag", Default: "testval"},
+			{Name: "version", Usage: "Display the application version", Default: "false"},
+		},
+		Commands: []CommandDescription{
+			{
+				Name:    "testcommand",
+				Summary: "A test command",
+				Usage:   "args here...",
+
+				Flags: []FlagDescription{
+					{Name: "help", Usage: "Display the help message", Default: "false"},
+					{Name: "testcommandflag", Type: "int", Usage: "A test command flag", Default: "5"},
+				},
+			},
+		},
+	}
+
+	got := app.Describe()
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("app.Describe gave %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright © 2026 Trevor N. Suarez (Rican7)
+
+package lieut
+
+import (
+	"context"
+	"io"
+	"os"
+
+	"github.com/Rican7/lieut/internal/runreport"
+)
+
+// Environment describes the environment of a run of an app, overriding that of
+// the process, such as for running an app in-process during tests.
+//
+// When a run has an Environment, the process' arguments aren't used if no
+// arguments are given, and interrupt signals aren't handled, so that multiple
+// runs can happen in parallel within the same process.
+type Environment struct {
+	// Stdout, Stderr, and Stdin override the app's writers and input reader,
+	// if set.
+	Stdout io.Writer
+	Stderr io.Writer
+	Stdin  io.Reader
+
+	// LookupEnv overrides the lookup of environment variables (os.LookupEnv),
+	// if set.
+	LookupEnv func(key string) (string, bool)
+
+	// Environ overrides the listing of environment variables (os.Environ), as
+	// "key=value" pairs, if set. They're the environment variables given to
+	// subprocesses, such as plugin commands. When LookupEnv is set without
+	// Environ, subprocesses aren't given any of the process' variables.
+	Environ func() []string
+
+	// Dir overrides the working directory that relative paths (such as those
+	// of response files) are resolved against, if set.
+	Dir string
+}
+
+type environmentContextKey struct{}
+
+// WithEnvironment returns a copy of the given context that holds the given
+// environment, for an app to run in when given the context.
+func WithEnvironment(ctx context.Context, env Environment) context.Context {
+	return context.WithValue(ctx, environmentContextKey{}, &env)
+}
+
+// useEnvironment applies the environment from the given context (if any) to
+// the app, along with any reporter of the run.
+//
+// It should only be called on a per-run copy of the app, as it replaces the
+// app's writers and input reader.
+func (a *app) useEnvironment(ctx context.Context) {
+	a.reporter = runreport.FromContext[CommandInfo](ctx)
+
+	env, ok := ctx.Value(environmentContextKey{}).(*Environment)
+	if !ok {
+		return
+	}
+
+	a.env = env
+
+	if env.Stdout != nil {
+		a.out = env.Stdout
+	}
+
+	if env.Stderr != nil {
+		a.errOut = env.Stderr
+	}
+
+	if env.Stdin != nil {
+		a.in = env.Stdin
+	}
+}
+
+// processArguments returns the arguments of the process, unless the app is
+// running in an overridden environment.
+func (a *app) processArguments() []string {
+	if a.env != nil {
+		return nil
+	}
+
+	return os.Args[1:]
+}
+
+// lookupEnv looks up the value of the given environment variable, from the
+// app's environment.
+func (a *app) lookupEnv(key string) (string, bool) {
+	if a.env != nil && a.env.LookupEnv != nil {
+		return a.env.LookupEnv(key)
+	}
+
+	return os.LookupEnv(key)
+}
+
+// workDir returns the directory that relative paths are resolved against, from
+// the app's environment, or empty for the process' working directory.
+func (a *app) workDir() string {
+	if a.env != nil {
+		return a.env.Dir
+	}
+
+	return ""
+}
+
+// environ returns the environment variables of the app's environment, as
+// "key=value" pairs.
+func (a *app) environ() []string {
+	switch {
+	case a.env != nil && a.env.Environ != nil:
+		return append([]string(nil), a.env.Environ()...)
+	case a.env != nil && a.env.LookupEnv != nil:
+		return nil
+	default:
+		return os.Environ()
+	}
+}
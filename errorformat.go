@@ -0,0 +1,242 @@
+// Copyright © 2026 Trevor N. Suarez (Rican7)
+
+package lieut
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// ErrorFormat defines the format that an app displays errors in.
+type ErrorFormat int
+
+// Error formats.
+const (
+	// ErrorFormatText displays errors as human-readable text. It's the default.
+	ErrorFormatText ErrorFormat = iota
+
+	// ErrorFormatJSON displays each error as a single JSON object, for apps
+	// that are driven by other programs. See ErrorKind for the object's fields.
+	ErrorFormatJSON
+)
+
+// errorFormatNames maps error formats to their names, as used by flags.
+var errorFormatNames = map[ErrorFormat]string{
+	ErrorFormatText: "text",
+	ErrorFormatJSON: "json",
+}
+
+// String returns the name of the format.
+func (f ErrorFormat) String() string {
+	if name, ok := errorFormatNames[f]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("format(%d)", int(f))
+}
+
+// Set sets the format from its name, so that the format can be used as the
+// value of a flag.
+func (f *ErrorFormat) Set(value string) error {
+	for format, name := range errorFormatNames {
+		if strings.EqualFold(value, name) {
+			*f = format
+			return nil
+		}
+	}
+
+	return fmt.Errorf("must be one of: %s", strings.Join(f.Choices(), ", "))
+}
+
+// Type returns the name of the type of the format's flag value.
+func (f *ErrorFormat) Type() string {
+	return "format"
+}
+
+// Choices returns the names of the formats.
+func (f *ErrorFormat) Choices() []string {
+	return []string{ErrorFormatText.String(), ErrorFormatJSON.String()}
+}
+
+// ErrorKind describes the kind of an error displayed by an app.
+//
+// When an app displays errors with ErrorFormatJSON, each error (or warning) is
+// a JSON object with the fields:
+//
+//	error      The error message.
+//	kind       The kind of the error.
+//	command    The full path of the command, such as "app deploy".
+//	exit_code  The exit code that the app returns.
+//	hint       A hint for resolving the error, if any.
+//	details    The messages of joined errors (see errors.Join), if any.
+type ErrorKind string
+
+// Error kinds.
+const (
+	// ErrorKindUsage is the kind of usage errors, such as invalid flags.
+	ErrorKindUsage ErrorKind = "usage"
+
+	// ErrorKindUnknownCommand is the kind of errors for unknown commands.
+	ErrorKindUnknownCommand ErrorKind = "unknown_command"
+
+	// ErrorKindRuntime is the kind of errors returned by an init function or a
+	// command's Executor.
+	ErrorKindRuntime ErrorKind = "runtime"
+
+	// ErrorKindHelpRequested is the kind of errors that request help (see
+	// ErrHelpRequested).
+	ErrorKindHelpRequested ErrorKind = "help_requested"
+
+	// ErrorKindDeprecation is the kind of warnings for the use of deprecated
+	// commands or flags, which don't stop the run (and so have an exit code of
+	// ExitCodeSuccess), unless deprecations are strict.
+	ErrorKindDeprecation ErrorKind = "deprecation"
+)
+
+// SetErrorFormat sets the format that the app displays errors in.
+//
+// By default, errors are displayed with ErrorFormatText.
+func (a *app) SetErrorFormat(format ErrorFormat) {
+	a.errorFormat = format
+}
+
+// ErrorFormatValue returns a flag value that sets the format that the app
+// displays errors in, such as for a global `--error-format` flag.
+//
+// The format set by the flag only applies to the run that the flag is parsed
+// for. The flag should be defined before any commands are set, so that it's
+// merged into the flags of each command.
+func (a *app) ErrorFormatValue() Value {
+	return &errorFormatValue{format: a.errorFormat}
+}
+
+// errorFormatValue is the flag value returned by ErrorFormatValue. As it may be
+// shared among runs, each run copies the format that it was set to from the
+// flags that it parsed.
+type errorFormatValue struct {
+	format ErrorFormat
+	isSet  bool
+}
+
+func (v *errorFormatValue) String() string {
+	return v.format.String()
+}
+
+func (v *errorFormatValue) Set(value string) error {
+	if err := v.format.Set(value); err != nil {
+		return err
+	}
+
+	v.isSet = true
+
+	return nil
+}
+
+func (v *errorFormatValue) Type() string {
+	return v.format.Type()
+}
+
+func (v *errorFormatValue) Choices() []string {
+	return v.format.Choices()
+}
+
+func (v *errorFormatValue) reset() {
+	v.isSet = false
+}
+
+// useErrorFormatFlag applies the format that an error format flag of the given
+// flags was set to (if any) to the run.
+func (a *app) useErrorFormatFlag(flags Flags) {
+	visitFlagValues(flags, func(value flag.Value) {
+		if formatValue, ok := value.(*errorFormatValue); ok && formatValue.isSet {
+			a.errorFormat = formatValue.format
+		}
+	})
+}
+
+// ErrorInfo describes an error displayed by an app.
+type ErrorInfo struct {
+	// Err is the error.
+	Err error
+
+	// Kind is the kind of the error.
+	Kind ErrorKind
+
+	// CommandPath is the full path of the command, such as "app deploy", or
+	// just the name of the app if no command was resolved.
+	CommandPath string
+
+	// Command is the info of the resolved command, for MultiCommandApps. It's
+	// nil if no command was resolved.
+	Command *CommandInfo
+
+	// ExitCode is the exit code that the app returns.
+	ExitCode int
+
+	// Hint is a hint for resolving the error, if any.
+	Hint string
+}
+
+// IsUsageError returns whether the error is a usage error, including unknown
+// commands.
+func (e ErrorInfo) IsUsageError() bool {
+	return e.Kind == ErrorKindUsage || e.Kind == ErrorKindUnknownCommand
+}
+
+// jsonError is the JSON representation of an ErrorInfo.
+type jsonError struct {
+	Error    string    `json:"error"`
+	Kind     ErrorKind `json:"kind"`
+	Command  string    `json:"command"`
+	ExitCode int       `json:"exit_code"`
+	Hint     string    `json:"hint,omitempty"`
+	Details  []string  `json:"details,omitempty"`
+}
+
+// isJSONErrorFormat returns whether the app displays errors as JSON.
+func (a *app) isJSONErrorFormat() bool {
+	return a.errorFormat == ErrorFormatJSON
+}
+
+// printJSONError prints the given error as a JSON object.
+func (a *app) printJSONError(e ErrorInfo) {
+	object := jsonError{
+		Kind:     e.Kind,
+		Command:  e.CommandPath,
+		ExitCode: e.ExitCode,
+		Hint:     e.Hint,
+	}
+
+	if e.Err != nil {
+		object.Error = e.Err.Error()
+
+		for _, err := range joinedErrors(e.Err) {
+			object.Details = append(object.Details, err.Error())
+		}
+	}
+
+	encoder := json.NewEncoder(a.errOut)
+	encoder.SetEscapeHTML(false)
+
+	_ = encoder.Encode(object)
+}
+
+// joinedErrors returns the errors joined by the first error in the given
+// error's chain that joins multiple errors (such as with errors.Join), if any.
+func joinedErrors(err error) []error {
+	var joined interface{ Unwrap() []error }
+	if errors.As(err, &joined) {
+		return joined.Unwrap()
+	}
+
+	return nil
+}
+
+// usageHint returns the hint displayed for usage errors of the command with the
+// given full path.
+func usageHint(commandPath string) string {
+	return fmt.Sprintf("Run '%s --help' for usage.", commandPath)
+}
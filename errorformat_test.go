@@ -0,0 +1,275 @@
+package lieut
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestErrorFormat_Set(t *testing.T) {
+	for testName, testData := range map[string]struct {
+		value string
+
+		want    ErrorFormat
+		wantErr bool
+	}{
+		"text": {
+			value: "text",
+
+			want: ErrorFormatText,
+		},
+		"json": {
+			value: "JSON",
+
+			want: ErrorFormatJSON,
+		},
+		"invalid": {
+			value: "xml",
+
+			wantErr: true,
+		},
+	} {
+		t.Run(testName, func(t *testing.T) {
+			var format ErrorFormat
+
+			err := format.Set(testData.value)
+
+			if (err != nil) != testData.wantErr {
+				t.Fatalf("ErrorFormat.Set returned error %v, want error %t", err, testData.wantErr)
+			}
+
+			if format != testData.want {
+				t.Errorf("ErrorFormat.Set gave %v, want %v", format, testData.want)
+			}
+		})
+	}
+}
+
+func TestMultiCommandApp_Run_JSONErrorFormat(t *testing.T) {
+	executor := func(ctx context.Context, arguments []string) error {
+		switch arguments[0] {
+		case "runtime":
+			return ErrWithStatusCode(errors.Join(errors.New("first"), errors.New("second")), 5)
+		case "help":
+			return ErrWithHelpRequested(errors.New("missing argument"))
+		case "silent":
+			return ErrWithStatusCode(errors.New(""), 3)
+		}
+
+		return nil
+	}
+
+	for testName, testData := range map[string]struct {
+		arguments []string
+
+		wantExitCode int
+		wantError    *jsonError
+	}{
+		"usage error": {
+			arguments: []string{"deploy", "-bogus"},
+
+			wantExitCode: ExitCodeUsageError,
+			wantError: &jsonError{
+				Error:    "flag provided but not defined: -bogus",
+				Kind:     ErrorKindUsage,
+				Command:  "test deploy",
+				ExitCode: ExitCodeUsageError,
+				Hint:     "Run 'test deploy --help' for usage.",
+			},
+		},
+		"unknown command": {
+			arguments: []string{"bogus"},
+
+			wantExitCode: ExitCodeError,
+			wantError: &jsonError{
+				Error:    "unknown command 'bogus'",
+				Kind:     ErrorKindUnknownCommand,
+				Command:  "test",
+				ExitCode: ExitCodeError,
+				Hint:     "Run 'test --help' for usage.",
+			},
+		},
+		"runtime error": {
+			arguments: []string{"deploy", "runtime"},
+
+			wantExitCode: 5,
+			wantError: &jsonError{
+				Error:    "first\nsecond",
+				Kind:     ErrorKindRuntime,
+				Command:  "test deploy",
+				ExitCode: 5,
+				Details:  []string{"first", "second"},
+			},
+		},
+		"help requested": {
+			arguments: []string{"deploy", "help"},
+
+			wantExitCode: ExitCodeUsageError,
+			wantError: &jsonError{
+				Error:    "missing argument",
+				Kind:     ErrorKindHelpRequested,
+				Command:  "test deploy",
+				ExitCode: ExitCodeUsageError,
+			},
+		},
+		"empty error": {
+			arguments: []string{"deploy", "silent"},
+
+			wantExitCode: 3,
+		},
+	} {
+		t.Run(testName, func(t *testing.T) {
+			var errOut bytes.Buffer
+
+			app := NewMultiCommandApp(testAppInfo, nil, io.Discard, &errOut)
+			app.SetErrorFormat(ErrorFormatJSON)
+			_ = app.SetCommand(CommandInfo{Name: "deploy"}, executor, nil)
+
+			if exitCode := app.Run(context.TODO(), testData.arguments); exitCode != testData.wantExitCode {
+				t.Errorf("app.Run gave %v, wanted %v", exitCode, testData.wantExitCode)
+			}
+
+			if testData.wantError == nil {
+				if errOut.Len() > 0 {
+					t.Errorf("app.Run gave errOut %q, wanted none", errOut.String())
+				}
+
+				return
+			}
+
+			var got jsonError
+			decoder := json.NewDecoder(&errOut)
+			if err := decoder.Decode(&got); err != nil {
+				t.Fatalf("app.Run gave invalid JSON %q: %v", errOut.String(), err)
+			}
+
+			if decoder.More() {
+				t.Errorf("app.Run gave more than a single JSON object")
+			}
+
+			if !reflect.DeepEqual(got, *testData.wantError) {
+				t.Errorf("app.Run gave error %+v, wanted %+v", got, *testData.wantError)
+			}
+		})
+	}
+}
+
+func TestSingleCommandApp_Run_ErrorFormatFlag(t *testing.T) {
+	var errOut bytes.Buffer
+
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags.Int("count", 0, "A count")
+
+	app := NewSingleCommandApp(testAppInfo, testNoOpExecutor, flags, io.Discard, &errOut)
+	flags.Var(app.ErrorFormatValue(), "error-format", "The format of errors")
+
+	arguments := []string{"-error-format=json", "-count=nope"}
+	if exitCode := app.Run(context.TODO(), arguments); exitCode != ExitCodeUsageError {
+		t.Errorf("app.Run gave %v, wanted %v", exitCode, ExitCodeUsageError)
+	}
+
+	want := `{"error":"invalid value \"nope\" for flag -count: parse error","kind":"usage",` +
+		`"command":"test","exit_code":2,"hint":"Run 'test --help' for usage."}` + "\n"
+	if got := errOut.String(); got != want {
+		t.Errorf("app.Run gave errOut %q, wanted %q", got, want)
+	}
+
+	// The format only applies to the run that the flag was parsed for
+	errOut.Reset()
+
+	if exitCode := app.Run(context.TODO(), []string{"-count=nope"}); exitCode != ExitCodeUsageError {
+		t.Errorf("app.Run gave %v, wanted %v", exitCode, ExitCodeUsageError)
+	}
+
+	if want := "Error: invalid value"; !strings.HasPrefix(errOut.String(), want) {
+		t.Errorf("app.Run gave errOut %q, wanted prefix %q", errOut.String(), want)
+	}
+}
+
+func TestMultiCommandApp_Run_JSONDeprecationWarnings(t *testing.T) {
+	var errOut bytes.Buffer
+
+	flags := flag.NewFlagSet("deploy", flag.ContinueOnError)
+	flags.Bool("force", false, "Force it")
+	_ = MarkFlagDeprecated(flags, "force", Deprecation{})
+
+	app := NewMultiCommandApp(testAppInfo, nil, io.Discard, &errOut)
+	app.SetErrorFormat(ErrorFormatJSON)
+	_ = app.SetCommand(CommandInfo{Name: "deploy", Deprecated: &Deprecation{}}, testNoOpExecutor, flags)
+
+	if exitCode := app.Run(context.TODO(), []string{"deploy", "-force"}); exitCode != ExitCodeSuccess {
+		t.Errorf("app.Run gave %v, wanted %v", exitCode, ExitCodeSuccess)
+	}
+
+	want := `{"error":"'deploy' is deprecated","kind":"deprecation","command":"test deploy","exit_code":0}` + "\n" +
+		`{"error":"'-force' is deprecated","kind":"deprecation","command":"test deploy","exit_code":0}` + "\n"
+	if got := errOut.String(); got != want {
+		t.Errorf("app.Run gave errOut %q, wanted %q", got, want)
+	}
+}
+
+func TestMultiCommandApp_Run_ErrorFormatFlagPerRun(t *testing.T) {
+	app := NewMultiCommandApp(testAppInfo, nil, io.Discard, io.Discard)
+
+	_ = app.SetCommandFunc(CommandInfo{Name: "deploy"}, func() (Flags, Executor, error) {
+		flags := flag.NewFlagSet("deploy", flag.ContinueOnError)
+		flags.Int("count", 0, "A count")
+		flags.Var(app.ErrorFormatValue(), "error-format", "The format of errors")
+
+		return flags, testNoOpExecutor, nil
+	})
+
+	const runs = 10
+
+	var wg sync.WaitGroup
+	errOuts := make([]bytes.Buffer, runs)
+
+	for i := 0; i < runs; i++ {
+		arguments := []string{"deploy", "-count=nope"}
+		if i%2 == 0 {
+			arguments = []string{"deploy", "-error-format=json", "-count=nope"}
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			ctx := WithEnvironment(context.TODO(), Environment{Stderr: &errOuts[i]})
+			app.Run(ctx, arguments)
+		}(i)
+	}
+
+	wg.Wait()
+
+	for i, errOut := range errOuts {
+		want := "Error: "
+		if i%2 == 0 {
+			want = `{"error":`
+		}
+
+		if !strings.HasPrefix(errOut.String(), want) {
+			t.Errorf("app.Run %d gave errOut %q, wanted prefix %q", i, errOut.String(), want)
+		}
+	}
+}
+
+func TestMultiCommandApp_PrintUsageError_JSONWithoutError(t *testing.T) {
+	var errOut bytes.Buffer
+
+	app := NewMultiCommandApp(testAppInfo, nil, io.Discard, &errOut)
+	app.SetErrorFormat(ErrorFormatJSON)
+	_ = app.SetCommand(CommandInfo{Name: "deploy"}, testNoOpExecutor, nil)
+
+	app.PrintUsageError("deploy", nil)
+
+	if want := "Usage: test deploy"; !strings.HasPrefix(errOut.String(), want) {
+		t.Errorf("app.PrintUsageError gave errOut %q, wanted prefix %q", errOut.String(), want)
+	}
+}
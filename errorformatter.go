@@ -0,0 +1,82 @@
+// Copyright © 2026 Trevor N. Suarez (Rican7)
+
+package lieut
+
+import (
+	"fmt"
+	"io"
+)
+
+// ErrorFormatter is a functional interface that displays an error, by writing
+// it to the given writer (the app's error output).
+//
+// It decides what's displayed for the error (if anything), while the app still
+// decides the exit code, and still displays any usage or help that accompanies
+// the error.
+type ErrorFormatter func(w io.Writer, e ErrorInfo)
+
+// DefaultErrorFormatter is the default ErrorFormatter, which displays the
+// error's message with an "Error: " prefix, unless there's no error or the
+// message is empty.
+func DefaultErrorFormatter(w io.Writer, e ErrorInfo) {
+	if e.Err == nil {
+		return
+	}
+
+	if msg := e.Err.Error(); msg != "" {
+		fmt.Fprintf(w, "Error: %s\n", msg)
+	}
+}
+
+// SetErrorFormatter sets the formatter that the app displays errors with, when
+// displaying errors with ErrorFormatText (the default).
+//
+// The formatter is given every error that the app displays, including errors
+// with empty messages (which DefaultErrorFormatter doesn't display), so it can
+// customize their presentation, such as by coloring a prefix or rendering joined
+// errors as a list. A nil formatter resets the app to DefaultErrorFormatter.
+func (a *app) SetErrorFormatter(formatter ErrorFormatter) {
+	a.errorFormatter = formatter
+}
+
+// displayError displays the given error according to the app's error format,
+// and returns whether anything was displayed.
+func (a *app) displayError(e ErrorInfo) bool {
+	if e.Err == nil {
+		return false
+	}
+
+	if a.isJSONErrorFormat() {
+		// Like the text format, don't display runtime errors without messages
+		if e.Kind == ErrorKindRuntime && e.Err.Error() == "" {
+			return false
+		}
+
+		a.printJSONError(e)
+		return true
+	}
+
+	formatter := a.errorFormatter
+	if formatter == nil {
+		formatter = DefaultErrorFormatter
+	}
+
+	out := &countingWriter{Writer: a.errOut}
+	formatter(out, e)
+
+	return out.count > 0
+}
+
+// countingWriter is a writer that counts the bytes written to it.
+type countingWriter struct {
+	io.Writer
+
+	count int
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.Writer.Write(p)
+	w.count += n
+
+	return n, err
+}
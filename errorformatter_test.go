@@ -0,0 +1,187 @@
+package lieut
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestMultiCommandApp_SetErrorFormatter(t *testing.T) {
+	executor := func(ctx context.Context, arguments []string) error {
+		switch arguments[0] {
+		case "runtime":
+			return ErrWithStatusCode(errors.New("went wrong"), 5)
+		case "help":
+			return ErrWithHelpRequested(errors.New("missing argument"))
+		}
+
+		return nil
+	}
+
+	for testName, testData := range map[string]struct {
+		arguments []string
+
+		wantExitCode     int
+		wantInfo         ErrorInfo
+		wantCommand      string
+		wantUsage        bool
+		wantErrOutPrefix string
+	}{
+		"usage error": {
+			arguments: []string{"deploy", "-bogus"},
+
+			wantExitCode: ExitCodeUsageError,
+			wantInfo: ErrorInfo{
+				Kind:        ErrorKindUsage,
+				CommandPath: "test deploy",
+				ExitCode:    ExitCodeUsageError,
+				Hint:        "Run 'test deploy --help' for usage.",
+			},
+			wantCommand:      "deploy",
+			wantUsage:        true,
+			wantErrOutPrefix: "! usage: flag provided but not defined: -bogus\n\nUsage: test deploy",
+		},
+		"unknown command": {
+			arguments: []string{"bogus"},
+
+			wantExitCode: ExitCodeError,
+			wantInfo: ErrorInfo{
+				Kind:        ErrorKindUnknownCommand,
+				CommandPath: "test",
+				ExitCode:    ExitCodeError,
+				Hint:        "Run 'test --help' for usage.",
+			},
+			wantUsage: true,
+		},
+		"runtime error": {
+			arguments: []string{"deploy", "runtime"},
+
+			wantExitCode: 5,
+			wantInfo: ErrorInfo{
+				Kind:        ErrorKindRuntime,
+				CommandPath: "test deploy",
+				ExitCode:    5,
+			},
+			wantCommand:      "deploy",
+			wantErrOutPrefix: "! runtime: went wrong\n",
+		},
+		"help requested": {
+			arguments: []string{"deploy", "help"},
+
+			wantExitCode: ExitCodeUsageError,
+			wantInfo: ErrorInfo{
+				Kind:        ErrorKindHelpRequested,
+				CommandPath: "test deploy",
+				ExitCode:    ExitCodeUsageError,
+			},
+			wantCommand:      "deploy",
+			wantErrOutPrefix: "! help_requested: missing argument\n\nUsage: test deploy",
+		},
+	} {
+		t.Run(testName, func(t *testing.T) {
+			var errOut bytes.Buffer
+			var got *ErrorInfo
+
+			app := NewMultiCommandApp(testAppInfo, nil, io.Discard, &errOut)
+			_ = app.SetCommand(CommandInfo{Name: "deploy"}, executor, nil)
+
+			app.SetErrorFormatter(func(w io.Writer, e ErrorInfo) {
+				got = &e
+				fmt.Fprintf(w, "! %s: %v\n", e.Kind, e.Err)
+			})
+
+			if exitCode := app.Run(context.TODO(), testData.arguments); exitCode != testData.wantExitCode {
+				t.Errorf("app.Run gave %v, wanted %v", exitCode, testData.wantExitCode)
+			}
+
+			if got == nil {
+				t.Fatal("app.Run didn't call the error formatter")
+			}
+
+			if got.Err == nil {
+				t.Errorf("app.Run gave the error formatter a nil error")
+			}
+
+			gotCommand := ""
+			if got.Command != nil {
+				gotCommand = got.Command.Name
+			}
+
+			if gotCommand != testData.wantCommand {
+				t.Errorf("app.Run gave the error formatter command %q, wanted %q", gotCommand, testData.wantCommand)
+			}
+
+			if got.IsUsageError() != testData.wantUsage {
+				t.Errorf("ErrorInfo.IsUsageError gave %t, wanted %t", got.IsUsageError(), testData.wantUsage)
+			}
+
+			gotInfo := *got
+			gotInfo.Err, gotInfo.Command = nil, nil
+
+			if gotInfo != testData.wantInfo {
+				t.Errorf("app.Run gave the error formatter %+v, wanted %+v", gotInfo, testData.wantInfo)
+			}
+
+			if !strings.HasPrefix(errOut.String(), testData.wantErrOutPrefix) {
+				t.Errorf("app.Run gave errOut %q, wanted prefix %q", errOut.String(), testData.wantErrOutPrefix)
+			}
+		})
+	}
+}
+
+func TestSingleCommandApp_SetErrorFormatter_Silent(t *testing.T) {
+	var errOut bytes.Buffer
+
+	app := NewSingleCommandApp(testAppInfo, func(ctx context.Context, arguments []string) error {
+		return ErrWithHelpRequested(errors.New("internal details"))
+	}, nil, io.Discard, &errOut)
+
+	app.SetErrorFormatter(func(w io.Writer, e ErrorInfo) {})
+
+	if exitCode := app.Run(context.TODO(), nil); exitCode != ExitCodeUsageError {
+		t.Errorf("app.Run gave %v, wanted %v", exitCode, ExitCodeUsageError)
+	}
+
+	// Without a displayed error, the help shouldn't be preceded by a spacer line
+	if got := errOut.String(); got == "" || got[0] == '\n' {
+		t.Errorf("app.Run gave errOut %q, wanted help without a leading spacer", got)
+	}
+}
+
+func TestDefaultErrorFormatter(t *testing.T) {
+	for testName, testData := range map[string]struct {
+		err error
+
+		want string
+	}{
+		"message": {
+			err: errors.New("went wrong"),
+
+			want: "Error: went wrong\n",
+		},
+		"empty message": {
+			err: errors.New(""),
+
+			want: "",
+		},
+		"no error": {
+			err: nil,
+
+			want: "",
+		},
+	} {
+		t.Run(testName, func(t *testing.T) {
+			var out bytes.Buffer
+
+			DefaultErrorFormatter(&out, ErrorInfo{Err: testData.err})
+
+			if got := out.String(); got != testData.want {
+				t.Errorf("DefaultErrorFormatter gave %q, wanted %q", got, testData.want)
+			}
+		})
+	}
+}
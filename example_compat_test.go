@@ -0,0 +1,45 @@
+// Copyright © 2026 Trevor N. Suarez (Rican7)
+
+package lieut_test
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/Rican7/lieut"
+)
+
+// previousDescription is a description of an app's previous release, as it
+// might be stored (as JSON) alongside the app's source.
+const previousDescription = `{
+	"name": "now",
+	"commands": [
+		{"name": "time", "flags": [{"name": "seconds", "usage": "to include seconds", "default": "false"}]},
+		{"name": "date", "flags": [{"name": "year", "usage": "to include year", "default": "false"}]}
+	]
+}`
+
+func ExampleCompareDescriptions() {
+	app := lieut.NewMultiCommandApp(lieut.AppInfo{Name: "now"}, nil, io.Discard, io.Discard)
+
+	timeFlags := flag.NewFlagSet("time", flag.ContinueOnError)
+	timeFlags.Bool("with-seconds", false, "to include seconds")
+
+	app.SetCommand(lieut.CommandInfo{Name: "time"}, nil, timeFlags)
+
+	var previous lieut.AppDescription
+	if err := json.Unmarshal([]byte(previousDescription), &previous); err != nil {
+		panic(err)
+	}
+
+	// In a test, this would typically fail the test via `t.Error`
+	for _, change := range lieut.CompareDescriptions(previous, app.Describe()).AtLeast(lieut.SeverityWarning) {
+		fmt.Println(change)
+	}
+
+	// Output:
+	// breaking: command 'time': flag 'seconds' was renamed to 'with-seconds'
+	// breaking: command 'date': command was removed
+}
@@ -7,7 +7,9 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"strings"
 
 	"github.com/Rican7/lieut"
 )
@@ -18,7 +20,7 @@ func Example_errWithHelpRequested() {
 			return lieut.ErrWithHelpRequested(errors.New("at least one argument is required"))
 		}
 
-		_, err := fmt.Println(arguments)
+		_, err := fmt.Fprintln(lieut.Stdout(ctx), arguments)
 
 		return err
 	}
@@ -42,7 +44,7 @@ func Example_errWithStatusCode() {
 			return lieut.ErrWithStatusCode(errors.New("at least one argument is required"), 2)
 		}
 
-		_, err := fmt.Println(arguments)
+		_, err := fmt.Fprintln(lieut.Stdout(ctx), arguments)
 
 		return err
 	}
@@ -69,7 +71,7 @@ func Example_errWithHelpRequestedAndStatusCode() {
 			)
 		}
 
-		_, err := fmt.Println(arguments)
+		_, err := fmt.Fprintln(lieut.Stdout(ctx), arguments)
 
 		return err
 	}
@@ -86,3 +88,56 @@ func Example_errWithHelpRequestedAndStatusCode() {
 
 	os.Exit(exitCode)
 }
+
+func Example_errorFormatter() {
+	flags := flag.NewFlagSet("example", flag.ExitOnError)
+	debug := flags.Bool("debug", false, "show the causes of errors")
+
+	do := func(ctx context.Context, arguments []string) error {
+		return fmt.Errorf(
+			"unable to sync: %w",
+			errors.Join(errors.New("remote unreachable"), errors.New("cache locked")),
+		)
+	}
+
+	app := lieut.NewSingleCommandApp(
+		lieut.AppInfo{Name: "example"},
+		do,
+		flags,
+		os.Stdout,
+		os.Stderr,
+	)
+
+	app.SetErrorFormatter(func(w io.Writer, e lieut.ErrorInfo) {
+		if e.IsUsageError() || !*debug {
+			lieut.DefaultErrorFormatter(w, e)
+			return
+		}
+
+		var joined interface {
+			error
+			Unwrap() []error
+		}
+		if !errors.As(e.Err, &joined) {
+			lieut.DefaultErrorFormatter(w, e)
+			return
+		}
+
+		// Display the error's own message, without the joined causes, and then
+		// each of the causes as a bullet list
+		message := strings.TrimSuffix(strings.TrimSuffix(e.Err.Error(), joined.Error()), ": ")
+		if message == "" {
+			message = "multiple errors occurred"
+		}
+
+		fmt.Fprintf(w, "\x1b[31mError:\x1b[0m %s\n", message)
+
+		for _, cause := range joined.Unwrap() {
+			fmt.Fprintf(w, "  - %s\n", cause)
+		}
+	})
+
+	exitCode := app.Run(context.Background(), os.Args[1:])
+
+	os.Exit(exitCode)
+}
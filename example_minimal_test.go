@@ -13,7 +13,7 @@ import (
 
 func Example_minimal() {
 	do := func(ctx context.Context, arguments []string) error {
-		_, err := fmt.Println(arguments)
+		_, err := fmt.Fprintln(lieut.Stdout(ctx), arguments)
 
 		return err
 	}
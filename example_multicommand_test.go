@@ -69,7 +69,7 @@ func printTime(ctx context.Context, arguments []string) error {
 		format += ":05"
 	}
 
-	_, err := fmt.Println(time.Now().Format(format))
+	_, err := fmt.Fprintln(lieut.Stdout(ctx), time.Now().Format(format))
 
 	return err
 }
@@ -81,7 +81,7 @@ func printDate(ctx context.Context, arguments []string) error {
 		format += "-2006"
 	}
 
-	_, err := fmt.Println(time.Now().Format(format))
+	_, err := fmt.Fprintln(lieut.Stdout(ctx), time.Now().Format(format))
 
 	return err
 }
@@ -39,7 +39,7 @@ func sayHello(ctx context.Context, arguments []string) error {
 	names := strings.Join(arguments, ", ")
 	hello := fmt.Sprintf("Hello %s!", names)
 
-	_, err := fmt.Println(hello)
+	_, err := fmt.Fprintln(lieut.Stdout(ctx), hello)
 
 	return err
 }
@@ -0,0 +1,54 @@
+// Copyright © 2026 Trevor N. Suarez (Rican7)
+
+package lieut_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Rican7/lieut"
+)
+
+type greetOptions struct {
+	Greeting string   `flag:"greeting" short:"g" usage:"The greeting to use" default:"Hello"`
+	Shout    bool     `flag:"shout" usage:"Greet loudly"`
+	Names    []string `arg:"names" required:"true"`
+}
+
+func ExampleStructFlags() {
+	var options greetOptions
+
+	flagSet, err := lieut.StructFlags("greet", &options)
+	if err != nil {
+		panic(err)
+	}
+
+	greet := func(ctx context.Context, arguments []string) error {
+		if err := lieut.BindArguments(&options, arguments); err != nil {
+			return err
+		}
+
+		greeting := fmt.Sprintf("%s %s!", options.Greeting, strings.Join(options.Names, " and "))
+		if options.Shout {
+			greeting = strings.ToUpper(greeting)
+		}
+
+		_, err := fmt.Fprintln(lieut.Stdout(ctx), greeting)
+
+		return err
+	}
+
+	app := lieut.NewSingleCommandApp(
+		lieut.AppInfo{Name: "greet", Usage: "[option]... <names>..."},
+		greet,
+		flagSet,
+		os.Stdout,
+		os.Stderr,
+	)
+
+	app.Run(context.Background(), []string{"-g", "Hi", "--shout", "Ana", "Bo"})
+
+	// Output: HI ANA AND BO!
+}
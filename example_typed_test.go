@@ -0,0 +1,51 @@
+// Copyright © 2026 Trevor N. Suarez (Rican7)
+
+package lieut_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Rican7/lieut"
+)
+
+type timeOptions struct {
+	TimeZone       string `flag:"timezone" usage:"the timezone to report in" default:"UTC"`
+	IncludeSeconds bool   `flag:"seconds" usage:"to include seconds"`
+}
+
+func ExampleSetTypedCommand() {
+	app := lieut.NewMultiCommandApp(multiAppInfo, nil, os.Stdout, os.Stderr)
+
+	err := lieut.SetTypedCommand(
+		app,
+		lieut.CommandInfo{Name: "time", Summary: "Show the time", Usage: "[options]"},
+		printTypedTime,
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	app.Run(context.Background(), []string{"time", "-timezone", "Etc/GMT-2", "-seconds"})
+
+	// Output: 02:00:00
+}
+
+func printTypedTime(ctx context.Context, options *timeOptions, arguments []string) error {
+	location, err := time.LoadLocation(options.TimeZone)
+	if err != nil {
+		return err
+	}
+
+	format := "15:04"
+
+	if options.IncludeSeconds {
+		format += ":05"
+	}
+
+	_, err = fmt.Fprintln(lieut.Stdout(ctx), time.Unix(0, 0).In(location).Format(format))
+
+	return err
+}
@@ -4,14 +4,22 @@ package lieut
 
 import (
 	"bytes"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"reflect"
+	"strconv"
 	"strings"
 )
 
 // Flags defines an interface for command flags.
+//
+// Marking a standard library flag (as hidden, deprecated, etc.) wraps its Value
+// in a value that forwards to the original, which can be retrieved through the
+// wrapper's `Unwrap() flag.Value` method. Flags should be marked before they're
+// given to an app, so that any flags merged from the globals into a command's
+// flags are marked as well.
 type Flags interface {
 	Parse(arguments []string) error
 	Args() []string
@@ -28,6 +36,30 @@ type boolShortFlagger interface {
 	BoolVarP(p *bool, name string, shorthand string, value bool, usage string)
 }
 
+type hiddenMarker interface {
+	MarkHidden(name string) error
+}
+
+type annotationSetter interface {
+	SetAnnotation(name string, key string, values []string) error
+}
+
+type flagSetter interface {
+	Set(name string, value string) error
+}
+
+type typedValue interface {
+	Type() string
+}
+
+type choicesValue interface {
+	Choices() []string
+}
+
+type lookupFlagger interface {
+	Lookup(name string) *flag.Flag
+}
+
 type lookupVarFlagger interface {
 	Lookup(name string) *flag.Flag
 	Var(value flag.Value, name string, usage string)
@@ -42,11 +74,404 @@ type visitAllFlagger interface {
 	VisitAll(fn func(*flag.Flag))
 }
 
+// visitFlagger defines an interface for visiting only the flags that have been
+// set, with the same compatibility considerations as visitAllFlagger.
+type visitFlagger interface {
+	Visit(fn func(*flag.Flag))
+}
+
 type flagSet struct {
 	Flags
 
 	requestedHelp    bool
 	requestedVersion bool
+
+	inherited map[string]bool // Names of flags merged in from the globals
+}
+
+// annotationDeprecated is the key used to annotate deprecated flags, for flag
+// implementations that support annotations (such as spf13/pflag).
+//
+// The annotation's values are the replacement and the message, respectively.
+const annotationDeprecated = "lieut_deprecated"
+
+// annotationRequired is the key used to annotate required flags, for flag
+// implementations that support annotations.
+const annotationRequired = "lieut_required"
+
+// annotationEnv is the key used to annotate the environment variable of flags,
+// for flag implementations that support annotations.
+const annotationEnv = "lieut_env"
+
+// annotationNegatable is the key used to annotate negatable flags, for flag
+// implementations that support annotations.
+const annotationNegatable = "lieut_negatable"
+
+// annotationAliasOf is the key used to annotate flags that are an alias of
+// another flag, for flag implementations that support annotations.
+//
+// The annotation's value is the name of the flag that the flag is an alias of.
+const annotationAliasOf = "lieut_alias_of"
+
+// flagAnnotations holds lieut-specific metadata about a flag.
+type flagAnnotations struct {
+	hidden      bool
+	deprecation *Deprecation
+	required    bool
+	env         string
+	negatable   bool
+
+	shorthand string // The registered shorthand alias of the flag
+	aliasOf   string // The name of the flag that this flag is an alias of
+}
+
+// annotatedValue wraps a standard library flag value to attach annotations to
+// its flag, while otherwise behaving exactly like the wrapped value.
+type annotatedValue struct {
+	flag.Value
+
+	annotations flagAnnotations
+}
+
+// String forwards to the wrapped value, guarding against the zero value that
+// the flag package creates when determining a flag's zero value.
+func (v *annotatedValue) String() string {
+	if v.Value == nil {
+		return ""
+	}
+
+	return v.Value.String()
+}
+
+// IsBoolFlag forwards the wrapped value's boolean flag status, so that the
+// flag can still be used without an explicit value.
+func (v *annotatedValue) IsBoolFlag() bool {
+	boolValue, ok := v.Value.(interface{ IsBoolFlag() bool })
+
+	return ok && boolValue.IsBoolFlag()
+}
+
+// Get forwards to the wrapped value's Get method, if it has one.
+func (v *annotatedValue) Get() any {
+	if getter, ok := v.Value.(flag.Getter); ok {
+		return getter.Get()
+	}
+
+	return v.Value.String()
+}
+
+// Type forwards to the wrapped value's Type method, if it has one.
+func (v *annotatedValue) Type() string {
+	if typed, ok := v.Value.(typedValue); ok {
+		return typed.Type()
+	}
+
+	return ""
+}
+
+// Unwrap returns the wrapped value, so that callers can retrieve the value that
+// they defined the flag with.
+func (v *annotatedValue) Unwrap() flag.Value {
+	return v.Value
+}
+
+// negatedValue wraps a boolean flag value, setting the inverse of any value
+// that it's set to.
+type negatedValue struct {
+	flag.Value
+}
+
+func (v *negatedValue) String() string {
+	if v.Value == nil {
+		return ""
+	}
+
+	return strconv.FormatBool(v.Value.String() != "true")
+}
+
+func (v *negatedValue) Set(s string) error {
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return strconv.ErrSyntax
+	}
+
+	return v.Value.Set(strconv.FormatBool(!b))
+}
+
+func (v *negatedValue) IsBoolFlag() bool {
+	return true
+}
+
+func (v *negatedValue) Type() string {
+	return "bool"
+}
+
+// MarkFlagHidden marks the named flag as hidden, so that it's omitted from help
+// output while still being accepted when parsing.
+//
+// Flags that provide their own MarkHidden method (such as spf13/pflag) are
+// marked using that method.
+func MarkFlagHidden(flags Flags, name string) error {
+	if marker, ok := flags.(hiddenMarker); ok {
+		return marker.MarkHidden(name)
+	}
+
+	annotations, err := annotateFlag(flags, name)
+	if err != nil {
+		return err
+	}
+
+	annotations.hidden = true
+
+	return nil
+}
+
+// MarkFlagDeprecated marks the named flag as deprecated, so that a warning is
+// displayed when it's used. The flag remains usable, but is annotated as
+// deprecated in help output.
+//
+// The deprecation's replacement should be the name of the replacement flag,
+// without any dash prefix.
+//
+// Flags that provide a SetAnnotation method (such as spf13/pflag) are marked
+// using that method.
+func MarkFlagDeprecated(flags Flags, name string, deprecation Deprecation) error {
+	if setter, ok := flags.(annotationSetter); ok {
+		return setter.SetAnnotation(
+			name,
+			annotationDeprecated,
+			[]string{deprecation.Replacement, deprecation.Message},
+		)
+	}
+
+	annotations, err := annotateFlag(flags, name)
+	if err != nil {
+		return err
+	}
+
+	annotations.deprecation = &deprecation
+
+	return nil
+}
+
+// MarkFlagRequired marks the named flag as required, so that it's a usage error
+// to run the app (or command) without the flag being set.
+//
+// A required flag is satisfied by its environment variable, if it has one (see
+// SetFlagEnv). Required flags are annotated as such in help output.
+//
+// Flags that provide a SetAnnotation method (such as spf13/pflag) are marked
+// using that method.
+func MarkFlagRequired(flags Flags, name string) error {
+	if setter, ok := flags.(annotationSetter); ok {
+		return setter.SetAnnotation(name, annotationRequired, []string{"true"})
+	}
+
+	annotations, err := annotateFlag(flags, name)
+	if err != nil {
+		return err
+	}
+
+	annotations.required = true
+
+	return nil
+}
+
+// SetFlagEnv sets the name of an environment variable for the named flag, so
+// that the flag is set from the environment variable's value when the flag
+// isn't otherwise provided. The environment variable is displayed alongside the
+// flag's usage in help output.
+//
+// Flags that provide a SetAnnotation method (such as spf13/pflag) are annotated
+// using that method.
+func SetFlagEnv(flags Flags, name string, env string) error {
+	if setter, ok := flags.(annotationSetter); ok {
+		return setter.SetAnnotation(name, annotationEnv, []string{env})
+	}
+
+	annotations, err := annotateFlag(flags, name)
+	if err != nil {
+		return err
+	}
+
+	annotations.env = env
+
+	return nil
+}
+
+// MarkFlagNegatable marks the named boolean flag as negatable, so that it can be
+// set to false with a `no-` prefixed form of its name, such as `--no-color`.
+// The flag is displayed as `--[no-]color` in help output.
+//
+// The negated form is an alias of the flag, so it's considered to set the flag
+// itself (taking precedence over any environment variable, for example).
+//
+// Flags that provide their own VarPF method (such as spf13/pflag) have the
+// negated form registered as a hidden flag using that method.
+func MarkFlagNegatable(flags Flags, name string) error {
+	negatedName := "no-" + name
+
+	lookupVar, ok := flags.(lookupVarFlagger)
+	if !ok {
+		return markFlagNegatableByReflection(flags, name, negatedName)
+	}
+
+	f := lookupVar.Lookup(name)
+	if f == nil {
+		return fmt.Errorf("flag '%s' is not defined", name)
+	}
+
+	if boolValue, ok := f.Value.(interface{ IsBoolFlag() bool }); !ok || !boolValue.IsBoolFlag() {
+		return fmt.Errorf("flag '%s' must be a boolean flag", name)
+	}
+
+	if existing := lookupVar.Lookup(negatedName); existing != nil {
+		return fmt.Errorf("flag '%s' is already defined", negatedName)
+	}
+
+	annotations, err := annotateFlag(flags, name)
+	if err != nil {
+		return err
+	}
+
+	annotations.negatable = true
+
+	value := f.Value.(*annotatedValue)
+	negated := &annotatedValue{
+		Value:       &negatedValue{Value: value.Value},
+		annotations: flagAnnotations{aliasOf: name},
+	}
+
+	lookupVar.Var(negated, negatedName, f.Usage)
+
+	return nil
+}
+
+// markFlagNegatableByReflection registers the negated form of the named flag
+// via reflection, for implementations that provide VarPF and Lookup methods
+// (like pflag).
+func markFlagNegatableByReflection(flags Flags, name string, negatedName string) error {
+	setter, canAnnotate := flags.(annotationSetter)
+	marker, canHide := flags.(hiddenMarker)
+
+	v := reflect.ValueOf(flags)
+	lookup := v.MethodByName("Lookup")
+	varPF := v.MethodByName("VarPF")
+
+	if !canAnnotate || !canHide || !lookup.IsValid() || !varPF.IsValid() {
+		return errors.New("provided flags don't support negatable flags")
+	}
+
+	if lookup.Type().NumIn() != 1 || lookup.Type().NumOut() != 1 || lookup.Type().Out(0).Kind() != reflect.Pointer {
+		return errors.New("provided flags don't support negatable flags")
+	}
+
+	if varPF.Type().NumIn() != 4 || varPF.Type().NumOut() != 1 || varPF.Type().Out(0).Kind() != reflect.Pointer {
+		return errors.New("provided flags don't support negatable flags")
+	}
+
+	f := lookup.Call([]reflect.Value{reflect.ValueOf(name)})[0]
+	if f.IsNil() {
+		return fmt.Errorf("flag '%s' is not defined", name)
+	}
+
+	value, ok := f.Elem().FieldByName("Value").Interface().(flag.Value)
+	if !ok {
+		return fmt.Errorf("flag '%s' must be a boolean flag", name)
+	}
+
+	if boolValue, ok := value.(interface{ IsBoolFlag() bool }); !ok || !boolValue.IsBoolFlag() {
+		return fmt.Errorf("flag '%s' must be a boolean flag", name)
+	}
+
+	if existing := lookup.Call([]reflect.Value{reflect.ValueOf(negatedName)})[0]; !existing.IsNil() {
+		return fmt.Errorf("flag '%s' is already defined", negatedName)
+	}
+
+	negated := reflect.ValueOf(&negatedValue{Value: value})
+	if !negated.Type().AssignableTo(varPF.Type().In(0)) {
+		return errors.New("provided flags don't support negatable flags")
+	}
+
+	usage := f.Elem().FieldByName("Usage")
+	negatedFlag := varPF.Call([]reflect.Value{
+		negated,
+		reflect.ValueOf(negatedName),
+		reflect.ValueOf(""),
+		usage,
+	})[0]
+
+	// Allow the negated form to be used without an explicit value
+	if noOptDefVal := negatedFlag.Elem().FieldByName("NoOptDefVal"); noOptDefVal.CanSet() {
+		noOptDefVal.SetString("true")
+	}
+
+	if err := marker.MarkHidden(negatedName); err != nil {
+		return err
+	}
+
+	if err := setter.SetAnnotation(negatedName, annotationAliasOf, []string{name}); err != nil {
+		return err
+	}
+
+	return setter.SetAnnotation(name, annotationNegatable, []string{"true"})
+}
+
+// SetFlagShorthand registers a single-character shorthand for the named flag,
+// so that the flag can be provided as either `-v` or `--verbose`, for example.
+// The shorthand is displayed alongside the flag's name in help output.
+//
+// Only flags from the standard library's flag package (or those compatible with
+// it) are supported, as other flag packages (like spf13/pflag) provide their own
+// means of defining shorthands.
+func SetFlagShorthand(flags Flags, name string, shorthand string) error {
+	if len(shorthand) != 1 {
+		return fmt.Errorf("shorthand '%s' must be a single character", shorthand)
+	}
+
+	lookupVar, ok := flags.(lookupVarFlagger)
+	if !ok {
+		return errors.New("provided flags don't support registering shorthands")
+	}
+
+	if existing := lookupVar.Lookup(shorthand); existing != nil {
+		return fmt.Errorf("shorthand '%s' is already defined", shorthand)
+	}
+
+	annotations, err := annotateFlag(flags, name)
+	if err != nil {
+		return err
+	}
+
+	f := lookupVar.Lookup(name)
+	value := f.Value.(*annotatedValue)
+
+	annotations.shorthand = shorthand
+	lookupVar.Var(&annotatedValue{Value: value.Value, annotations: flagAnnotations{aliasOf: name}}, shorthand, f.Usage)
+
+	return nil
+}
+
+// annotateFlag returns the annotations for the named flag, wrapping the flag's
+// value to hold them if it hasn't been already.
+func annotateFlag(flags Flags, name string) (*flagAnnotations, error) {
+	lookup, ok := flags.(lookupFlagger)
+	if !ok {
+		return nil, errors.New("provided flags don't support annotations")
+	}
+
+	f := lookup.Lookup(name)
+	if f == nil {
+		return nil, fmt.Errorf("flag '%s' is not defined", name)
+	}
+
+	value, ok := f.Value.(*annotatedValue)
+	if !ok {
+		value = &annotatedValue{Value: f.Value}
+		f.Value = value
+	}
+
+	return &value.annotations, nil
 }
 
 func createDefaultFlags(name string) *flag.FlagSet {
@@ -85,9 +510,22 @@ func (a *app) setupFlagSet(flagSet *flagSet, isRoot bool) {
 
 	helpDescription := "Display the help message"
 
+	// Don't take the shorthand if it's already in use (which causes panics...),
+	// or if it belongs to a global flag that will be merged into the flags
+	helpShorthandAvailable := isShorthandAvailable(flagSet.Flags, "h")
+	if !isRoot {
+		if name, inUse := shorthandFlagName(a.flags.Flags, "h"); inUse && name != "help" {
+			helpShorthandAvailable = false
+		}
+	}
+
 	switch flags := flagSet.Flags.(type) {
 	case boolShortFlagger:
-		flags.BoolVarP(&flagSet.requestedHelp, "help", "h", flagSet.requestedHelp, helpDescription)
+		if helpShorthandAvailable {
+			flags.BoolVarP(&flagSet.requestedHelp, "help", "h", flagSet.requestedHelp, helpDescription)
+		} else if flags, ok := flags.(boolFlagger); ok {
+			flags.BoolVar(&flagSet.requestedHelp, "help", flagSet.requestedHelp, helpDescription)
+		}
 	case boolFlagger:
 		flags.BoolVar(&flagSet.requestedHelp, "help", flagSet.requestedHelp, helpDescription)
 	}
@@ -102,24 +540,262 @@ func (a *app) setupFlagSet(flagSet *flagSet, isRoot bool) {
 				"Display the application version",
 			)
 		}
-	} else {
-		globalFlags, globalFlagsOk := a.flags.Flags.(visitAllFlagger)
-		flags, flagsOk := flagSet.Flags.(lookupVarFlagger)
-
-		if globalFlagsOk && flagsOk {
-			// Loop through the globals and merge them into the specifics
-			globalFlags.VisitAll(func(flag *flag.Flag) {
-				// Don't override any existing flags (which causes panics...)
-				if existing := flags.Lookup(flag.Name); existing == nil {
-					// Don't merge the version flag, as it should only be
-					// available on the root/global flag set
-					if flag.Name != "version" {
-						flags.Var(flag.Value, flag.Name, flag.Usage)
-					}
-				}
-			})
+	}
+}
+
+// mergeGlobalFlags merges the app's global/shared flags into the given command
+// flags, so that they can be provided after the command name.
+//
+// Flags already defined by the command take precedence over the globals. It
+// returns an error if a global flag's shorthand conflicts with a different flag
+// of the command.
+func (a *app) mergeGlobalFlags(flagSet *flagSet) error {
+	globalFlags, globalFlagsOk := a.flags.Flags.(visitAllFlagger)
+	flags, flagsOk := flagSet.Flags.(lookupVarFlagger)
+
+	if !globalFlagsOk || !flagsOk {
+		// Otherwise, try reflection for other implementations (like pflag)
+		return mergeFlagsByReflection(a.flags.Flags, flagSet)
+	}
+
+	// Loop through the globals and merge them into the specifics
+	globalFlags.VisitAll(func(flag *flag.Flag) {
+		// Don't override any existing flags (which causes panics...)
+		if existing := flags.Lookup(flag.Name); existing == nil {
+			// Don't merge the version flag, as it should only be available on
+			// the root/global flag set
+			if flag.Name != "version" {
+				flags.Var(flag.Value, flag.Name, flag.Usage)
+				flagSet.markInherited(flag.Name)
+
+				// Keep the global's default, rather than its current value
+				flags.Lookup(flag.Name).DefValue = flag.DefValue
+			}
+		}
+	})
+
+	return nil
+}
+
+// mergeFlagsByReflection merges the given global flags into the given command
+// flags via reflection, for implementations that merge flags by adding the
+// flags themselves (like pflag's AddFlag method).
+func mergeFlagsByReflection(globals Flags, flagSet *flagSet) error {
+	flags := reflect.ValueOf(flagSet.Flags)
+
+	visitAll := reflect.ValueOf(globals).MethodByName("VisitAll")
+	addFlag := flags.MethodByName("AddFlag")
+	lookup := flags.MethodByName("Lookup")
+
+	if !visitAll.IsValid() || !addFlag.IsValid() || !lookup.IsValid() {
+		return nil
+	}
+
+	callbackType := visitAll.Type()
+	if callbackType.NumIn() != 1 || callbackType.In(0).Kind() != reflect.Func {
+		return nil
+	}
+
+	callbackType = callbackType.In(0)
+	if callbackType.NumIn() != 1 {
+		return nil
+	}
+
+	flagType := callbackType.In(0)
+	if flagType.Kind() != reflect.Pointer || flagType.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+
+	// Make sure the flags are compatible with each other
+	if addFlag.Type().NumIn() != 1 || addFlag.Type().In(0) != flagType {
+		return nil
+	}
+
+	if lookup.Type().NumIn() != 1 || lookup.Type().In(0).Kind() != reflect.String || lookup.Type().NumOut() != 1 {
+		return nil
+	}
+
+	// Check all of the globals for conflicts before merging any of them, so
+	// that the command's flags aren't left partially merged
+	var merged []reflect.Value
+	var names []string
+	var err error
+	callback := reflect.MakeFunc(callbackType, func(args []reflect.Value) []reflect.Value {
+		f := args[0]
+		if err != nil || f.IsNil() {
+			return nil
+		}
+
+		name := f.Elem().FieldByName("Name")
+		if !name.IsValid() || name.Kind() != reflect.String {
+			return nil
 		}
+
+		// Don't merge the version flag, as it should only be available on the
+		// root/global flag set
+		if name.String() == "version" {
+			return nil
+		}
+
+		// Don't override any existing flags
+		if existing := lookup.Call([]reflect.Value{name})[0]; !existing.IsNil() {
+			return nil
+		}
+
+		shorthand := f.Elem().FieldByName("Shorthand")
+		if shorthand.IsValid() && shorthand.Kind() == reflect.String && shorthand.String() != "" {
+			if !isShorthandAvailable(flagSet.Flags, shorthand.String()) {
+				err = fmt.Errorf(
+					"global flag '%s' shorthand '-%s' conflicts with an existing flag",
+					name.String(),
+					shorthand.String(),
+				)
+				return nil
+			}
+		}
+
+		merged = append(merged, f)
+		names = append(names, name.String())
+
+		return nil
+	})
+
+	visitAll.Call([]reflect.Value{callback})
+
+	if err != nil {
+		return err
+	}
+
+	for i, f := range merged {
+		addFlag.Call([]reflect.Value{f})
+		flagSet.markInherited(names[i])
+	}
+
+	return nil
+}
+
+// isShorthandAvailable returns whether the given shorthand is not yet in use by
+// any of the given flags.
+//
+// It's checked via reflection, for implementations that support shorthands and
+// provide a ShorthandLookup method (like pflag). Flags without that method are
+// assumed to have the shorthand available.
+func isShorthandAvailable(flags Flags, shorthand string) bool {
+	_, inUse := shorthandFlagName(flags, shorthand)
+
+	return !inUse
+}
+
+// shorthandFlagName returns the name of the flag that uses the given shorthand,
+// if any, with the same considerations as isShorthandAvailable.
+func shorthandFlagName(flags Flags, shorthand string) (string, bool) {
+	lookup := reflect.ValueOf(flags).MethodByName("ShorthandLookup")
+	if !lookup.IsValid() {
+		return "", false
+	}
+
+	lookupType := lookup.Type()
+	if lookupType.NumIn() != 1 || lookupType.In(0).Kind() != reflect.String || lookupType.NumOut() != 1 {
+		return "", false
+	}
+
+	existing := lookup.Call([]reflect.Value{reflect.ValueOf(shorthand)})[0]
+
+	switch existing.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if existing.IsNil() {
+			return "", false
+		}
+	default:
+		return "", false
+	}
+
+	name := reflect.Indirect(existing).FieldByName("Name")
+	if name.IsValid() && name.Kind() == reflect.String {
+		return name.String(), true
+	}
+
+	return "", true
+}
+
+// markInherited records that the named flag was merged in from the globals.
+func (f *flagSet) markInherited(name string) {
+	if f.inherited == nil {
+		f.inherited = make(map[string]bool)
+	}
+
+	f.inherited[name] = true
+}
+
+// resetter is implemented by the flag values of this package that hold state
+// about the run that they're parsed for.
+type resetter interface {
+	reset()
+}
+
+// resetRunState resets the run state of each of the given flags' values that
+// are provided by this package, so that a run isn't affected by the state of a
+// previous run. The values themselves, and any other values, are left as is.
+func resetRunState(flags Flags) {
+	visitFlagValues(flags, func(value flag.Value) {
+		if resetter, ok := value.(resetter); ok {
+			resetter.reset()
+		}
+	})
+}
+
+// unwrapFlags returns the flags wrapped by our internal flagSet, if the given
+// flags are one, so that the underlying flags can be inspected directly.
+func unwrapFlags(flags Flags) Flags {
+	if fs, ok := flags.(*flagSet); ok {
+		return fs.Flags
+	}
+
+	return flags
+}
+
+// visitFlagValues attempts to visit the values of all flags, supporting both
+// the standard library and third-party libraries (via reflection).
+func visitFlagValues(flags Flags, fn func(flag.Value)) {
+	flags = unwrapFlags(flags)
+
+	// Unwrap annotated values, so that the original values are visited
+	visit := func(value flag.Value) {
+		if wrapped, ok := value.(*annotatedValue); ok {
+			value = wrapped.Value
+		}
+
+		fn(value)
+	}
+
+	if vf, ok := flags.(visitAllFlagger); ok {
+		vf.VisitAll(func(f *flag.Flag) {
+			visit(f.Value)
+		})
+		return
+	}
+
+	visitAll := reflect.ValueOf(flags).MethodByName("VisitAll")
+	if !visitAll.IsValid() || visitAll.Type().NumIn() != 1 || visitAll.Type().In(0).Kind() != reflect.Func {
+		return
 	}
+
+	callback := reflect.MakeFunc(visitAll.Type().In(0), func(args []reflect.Value) []reflect.Value {
+		f := reflect.Indirect(args[0])
+		if f.Kind() != reflect.Struct {
+			return nil
+		}
+
+		if valueField := f.FieldByName("Value"); valueField.IsValid() {
+			if value, ok := valueField.Interface().(flag.Value); ok {
+				visit(value)
+			}
+		}
+
+		return nil
+	})
+
+	visitAll.Call([]reflect.Value{callback})
 }
 
 // flagInfo normalizes flag data across different flag library implementations.
@@ -128,16 +804,24 @@ type flagInfo struct {
 	shorthand string
 	usage     string
 	defValue  string
+	value     string
 	typeName  string
+	hidden    bool
+	boolean   bool // Whether the flag can be provided without a value
+
+	deprecation *Deprecation
+	required    bool
+	env         string
+	negatable   bool
+	choices     []string
+
+	aliasOf string
 }
 
-// printFlagDefaults wraps the writing of flag default values.
+// printFlagDefaults wraps the writing of flag default values to the app's error
+// output.
 func (a *app) printFlagDefaults(flags Flags) {
-	// Unwrap our internal flagSet if necessary
-	inner := flags
-	if fs, ok := flags.(*flagSet); ok {
-		inner = fs.Flags
-	}
+	inner := unwrapFlags(flags)
 
 	// Visit and categorize flags for reordering
 	userFlags, version, help, visited := categorizeFlagsByName(inner)
@@ -151,8 +835,8 @@ func (a *app) printFlagDefaults(flags Flags) {
 		flags.SetOutput(originalOut)
 
 		if buffer.Len() > 0 {
-			fmt.Fprintf(originalOut, "\nOptions:\n\n")
-			buffer.WriteTo(originalOut)
+			fmt.Fprintf(a.errOut, "\nOptions:\n\n")
+			buffer.WriteTo(a.errOut)
 		}
 		return
 	}
@@ -170,13 +854,7 @@ func (a *app) printFlagDefaults(flags Flags) {
 		return
 	}
 
-	// Determine dash prefix based on the flag implementation.
-	// Standard library flag uses single-dash, while other implementations
-	// (like spf13/pflag) use double-dash by convention.
-	dashPrefix := "--"
-	if _, isStd := inner.(*flag.FlagSet); isStd {
-		dashPrefix = "-"
-	}
+	dashPrefix := flagDashPrefix(inner)
 
 	// Check if any flags have shorthands to decide on column layout
 	hasShorthands := false
@@ -198,27 +876,164 @@ func (a *app) printFlagDefaults(flags Flags) {
 	}
 
 	// Print standardized, tab-aligned options
-	out := flags.Output()
+	out := a.errOut
 	fmt.Fprintf(out, "\nOptions:\n\n")
 	for i, f := range all {
 		fmt.Fprintf(out, "\t%-[1]*s\t%s", maxLen, formattedNames[i], f.usage)
 
+		if len(f.choices) > 0 {
+			fmt.Fprintf(out, " (one of: %s)", strings.Join(f.choices, ", "))
+		}
+
+		if f.deprecation != nil {
+			fmt.Fprint(out, " (deprecated)")
+		}
+
+		if f.required {
+			fmt.Fprint(out, " (required)")
+		}
+
 		// Print default value if it's non-zero
 		if f.defValue != "" && f.defValue != "false" && f.defValue != "0" && f.defValue != `""` {
 			def := f.defValue
-			if (f.typeName == "string" || f.typeName == "") && !strings.HasPrefix(def, `"`) {
+
+			// Boolean flags have no type name, but their defaults aren't strings
+			isBool := f.typeName == "" && def == "true"
+
+			if (f.typeName == "string" || f.typeName == "") && !isBool && !strings.HasPrefix(def, `"`) {
 				def = fmt.Sprintf("%q", def)
 			}
 			fmt.Fprintf(out, " (default %s)", def)
 		}
+
+		if f.env != "" {
+			fmt.Fprintf(out, " (env $%s)", f.env)
+		}
 		fmt.Fprintln(out)
 	}
 }
 
-// categorizeFlagsByName visits all flags and separates them into user-defined
-// flags, and the special version and help flags.
+// deprecatedFlagNotices returns deprecation notices for each of the deprecated
+// flags that have been set.
+func deprecatedFlagNotices(flags Flags) []string {
+	flags = unwrapFlags(flags)
+
+	dashPrefix := flagDashPrefix(flags)
+
+	var notices []string
+	visitSetFlags(flags, func(f flagInfo) {
+		if f.deprecation == nil {
+			return
+		}
+
+		replacement := f.deprecation.Replacement
+		if replacement != "" {
+			replacement = dashPrefix + replacement
+		}
+
+		notices = append(notices, f.deprecation.notice(dashPrefix+f.name, replacement))
+	})
+
+	return notices
+}
+
+// resolveFlags sets any unset flags that have an environment variable from the
+// environment, and then checks that all required flags have been set.
+//
+// It returns the names of the flags that were set from the environment.
+func resolveFlags(flags Flags, lookupEnv func(key string) (string, bool)) (map[string]bool, error) {
+	flags = unwrapFlags(flags)
+
+	fromEnv, err := setFlagsFromEnv(flags, lookupEnv)
+	if err != nil {
+		return nil, err
+	}
+
+	isSet := make(map[string]bool)
+	visitSetFlags(flags, func(f flagInfo) {
+		isSet[f.name] = true
+	})
+
+	var missing []string
+	visitFlags(flags, func(f flagInfo) {
+		if f.required && !isSet[f.name] && !fromEnv[f.name] {
+			missing = append(missing, f.name)
+		}
+	})
+
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("flag '%s%s' is required", flagDashPrefix(flags), missing[0])
+	}
+
+	return fromEnv, nil
+}
+
+// setFlagsFromEnv sets any unset flags that have an environment variable from
+// the environment, and returns the names of the flags that were set.
+func setFlagsFromEnv(flags Flags, lookupEnv func(key string) (string, bool)) (map[string]bool, error) {
+	flags = unwrapFlags(flags)
+
+	fromEnv := make(map[string]bool)
+
+	setter, canSet := flags.(flagSetter)
+	if !canSet {
+		return fromEnv, nil
+	}
+
+	isSet := make(map[string]bool)
+	visitSetFlags(flags, func(f flagInfo) {
+		isSet[f.name] = true
+	})
+
+	var unset []flagInfo
+	visitFlags(flags, func(f flagInfo) {
+		if !isSet[f.name] && f.env != "" {
+			unset = append(unset, f)
+		}
+	})
+
+	for _, f := range unset {
+		if value, hasValue := lookupEnv(f.env); hasValue {
+			if err := setter.Set(f.name, value); err != nil {
+				return nil, fmt.Errorf("invalid value %q for environment variable %s: %v", value, f.env, err)
+			}
+
+			fromEnv[f.name] = true
+		}
+	}
+
+	return fromEnv, nil
+}
+
+// flagDashPrefix determines the dash prefix for flag names based on the flag
+// implementation. Standard library flag uses single-dash, while other
+// implementations (like spf13/pflag) use double-dash by convention.
+//
+// Standard library flags with registered shorthands also use double-dash, to
+// distinguish the flag names from their shorthands.
+func flagDashPrefix(flags Flags) string {
+	if _, isStd := flags.(*flag.FlagSet); !isStd {
+		return "--"
+	}
+
+	dashPrefix := "-"
+	visitFlags(flags, func(f flagInfo) {
+		if f.shorthand != "" {
+			dashPrefix = "--"
+		}
+	})
+
+	return dashPrefix
+}
+
+// categorizeFlagsByName visits all visible flags and separates them into
+// user-defined flags, and the special version and help flags.
 func categorizeFlagsByName(flags Flags) (userFlags []flagInfo, version, help *flagInfo, visited bool) {
 	visited = visitFlags(flags, func(f flagInfo) {
+		if f.hidden {
+			return
+		}
+
 		switch f.name {
 		case "help":
 			help = &f
@@ -245,6 +1060,11 @@ func formatFlagName(f flagInfo, dashPrefix string, hasShorthands bool) string {
 	}
 
 	sb.WriteString(dashPrefix)
+
+	if f.negatable {
+		sb.WriteString("[no-]")
+	}
+
 	sb.WriteString(f.name)
 
 	if f.typeName != "" && f.typeName != "bool" {
@@ -256,23 +1076,125 @@ func formatFlagName(f flagInfo, dashPrefix string, hasShorthands bool) string {
 
 // visitFlags attempts to visit all flags in a generic way, supporting both
 // the standard library and third-party libraries (via reflection).
+//
+// Aliases of flags (such as registered shorthands) aren't visited, as they're
+// represented by the flags that they're an alias of.
 func visitFlags(flags Flags, fn func(flagInfo)) bool {
-	// Try standard library visitor pattern
+	visit := func(info flagInfo) {
+		if info.aliasOf == "" {
+			fn(info)
+		}
+	}
+
 	if vf, ok := flags.(visitAllFlagger); ok {
 		vf.VisitAll(func(f *flag.Flag) {
-			typeName, usage := flag.UnquoteUsage(f)
-			fn(flagInfo{
-				name:     f.Name,
-				usage:    usage,
-				defValue: f.DefValue,
-				typeName: typeName,
-			})
+			visit(stdFlagInfo(f))
 		})
 		return true
 	}
 
-	// Otherwise, try reflection for other implementations (like pflag)
-	m := reflect.ValueOf(flags).MethodByName("VisitAll")
+	return visitFlagsByReflection(flags, "VisitAll", visit)
+}
+
+// visitSetFlags attempts to visit only the flags that have been set, in the
+// same generic way as visitFlags.
+//
+// Aliases of flags that have been set are visited as the flags that they're an
+// alias of.
+func visitSetFlags(flags Flags, fn func(flagInfo)) bool {
+	var set []flagInfo
+	collect := func(info flagInfo) {
+		set = append(set, info)
+	}
+
+	var visited bool
+	if vf, ok := flags.(visitFlagger); ok {
+		vf.Visit(func(f *flag.Flag) {
+			collect(stdFlagInfo(f))
+		})
+		visited = true
+	} else {
+		visited = visitFlagsByReflection(flags, "Visit", collect)
+	}
+
+	var originals map[string]flagInfo
+	seen := make(map[string]bool)
+
+	for _, info := range set {
+		if info.aliasOf != "" {
+			// Lazily gather the original flags, as aliases are rarely set
+			if originals == nil {
+				originals = make(map[string]flagInfo)
+				visitFlags(flags, func(f flagInfo) {
+					originals[f.name] = f
+				})
+			}
+
+			if original, ok := originals[info.aliasOf]; ok {
+				info = original
+			}
+		}
+
+		if !seen[info.name] {
+			seen[info.name] = true
+			fn(info)
+		}
+	}
+
+	return visited
+}
+
+// stdFlagInfo returns the normalized info of a standard library flag.
+func stdFlagInfo(f *flag.Flag) flagInfo {
+	var annotations flagAnnotations
+
+	// Unwrap annotated values, so that the type of the original value can be
+	// determined
+	if value, ok := f.Value.(*annotatedValue); ok {
+		unwrapped := *f
+		unwrapped.Value = value.Value
+
+		f = &unwrapped
+		annotations = value.annotations
+	}
+
+	typeName, usage := flag.UnquoteUsage(f)
+
+	// Use the type name reported by the value itself, if the flag package
+	// couldn't determine one
+	if typed, ok := f.Value.(typedValue); ok && typeName == "value" {
+		typeName = typed.Type()
+	}
+
+	var choices []string
+	if choicer, ok := f.Value.(choicesValue); ok {
+		choices = choicer.Choices()
+	}
+
+	boolValue, isBool := f.Value.(interface{ IsBoolFlag() bool })
+
+	return flagInfo{
+		name:        f.Name,
+		shorthand:   annotations.shorthand,
+		usage:       usage,
+		defValue:    f.DefValue,
+		value:       f.Value.String(),
+		typeName:    typeName,
+		hidden:      annotations.hidden,
+		boolean:     isBool && boolValue.IsBoolFlag(),
+		deprecation: annotations.deprecation,
+		required:    annotations.required,
+		env:         annotations.env,
+		negatable:   annotations.negatable,
+		choices:     choices,
+		aliasOf:     annotations.aliasOf,
+	}
+}
+
+// visitFlagsByReflection attempts to visit flags via the named visitor method,
+// for implementations other than the standard library (like pflag).
+func visitFlagsByReflection(flags Flags, methodName string, fn func(flagInfo)) bool {
+	m := reflect.ValueOf(flags).MethodByName(methodName)
 	if !m.IsValid() || m.Type().NumIn() != 1 {
 		return false
 	}
@@ -296,17 +1218,66 @@ func visitFlags(flags Flags, fn func(flagInfo)) bool {
 			return ""
 		}
 
+		getBool := func(name string) bool {
+			field := f.FieldByName(name)
+			if field.IsValid() && field.Kind() == reflect.Bool {
+				return field.Bool()
+			}
+			return false
+		}
+
 		info := flagInfo{
 			name:      getStr("Name"),
 			shorthand: getStr("Shorthand"),
 			usage:     getStr("Usage"),
 			defValue:  getStr("DefValue"),
+			hidden:    getBool("Hidden"),
+
+			// Flags with a value for when they're provided alone are boolean-like
+			boolean: getStr("NoOptDefVal") != "",
 		}
 
 		if valField := f.FieldByName("Value"); valField.IsValid() {
-			if tv, ok := valField.Interface().(interface{ Type() string }); ok {
+			if stringer, ok := valField.Interface().(fmt.Stringer); ok {
+				info.value = stringer.String()
+			}
+
+			if tv, ok := valField.Interface().(typedValue); ok {
 				info.typeName = tv.Type()
 			}
+
+			if choicer, ok := valField.Interface().(choicesValue); ok {
+				info.choices = choicer.Choices()
+			}
+		}
+
+		if annotationsField := f.FieldByName("Annotations"); annotationsField.IsValid() {
+			if annotations, ok := annotationsField.Interface().(map[string][]string); ok {
+				if values, ok := annotations[annotationDeprecated]; ok && len(values) == 2 {
+					info.deprecation = &Deprecation{Replacement: values[0], Message: values[1]}
+				}
+
+				if values, ok := annotations[annotationRequired]; ok && len(values) == 1 {
+					info.required = values[0] == "true"
+				}
+
+				if values, ok := annotations[annotationEnv]; ok && len(values) == 1 {
+					info.env = values[0]
+				}
+
+				if values, ok := annotations[annotationNegatable]; ok && len(values) == 1 {
+					info.negatable = values[0] == "true"
+				}
+
+				if values, ok := annotations[annotationAliasOf]; ok && len(values) == 1 {
+					info.aliasOf = values[0]
+				}
+			}
+		}
+
+		// Support the implementation's own deprecation mechanism
+		if message := getStr("Deprecated"); message != "" && info.deprecation == nil {
+			info.deprecation = &Deprecation{Message: message}
 		}
 
 		if info.name != "" {
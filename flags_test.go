@@ -6,7 +6,10 @@ import (
 	"flag"
 	"fmt"
 	"io"
+	"os"
+	"reflect"
 	"testing"
+	"time"
 )
 
 type bogusFlags string
@@ -106,6 +109,10 @@ type mockReflectFlag struct {
 	Usage     string
 	DefValue  string
 	Value     mockFlagValue
+	Hidden    bool
+
+	Deprecated  string
+	Annotations map[string][]string
 }
 
 // reflectableFlags is a Flags implementation with a VisitAll method that uses a
@@ -182,6 +189,16 @@ func TestPrintFlagDefaults(t *testing.T) {
 			flags: flag.NewFlagSet("empty", flag.ContinueOnError),
 			want:  "",
 		},
+		"reflectable flags with hidden flags": {
+			flags: &reflectableFlags{
+				flags: []mockReflectFlag{
+					{Name: "output", Usage: "Output file", Value: "string"},
+					{Name: "legacy", Usage: "A legacy flag", Value: "bool", Hidden: true},
+				},
+			},
+			want: "\nOptions:\n\n" +
+				"\t--output string\tOutput file\n",
+		},
 		"reflectable flags with shorthands": {
 			flags: &reflectableFlags{
 				flags: []mockReflectFlag{
@@ -195,12 +212,47 @@ func TestPrintFlagDefaults(t *testing.T) {
 				"\t    --verbose      \tEnable verbose output\n" +
 				"\t-h, --help         \tDisplay the help message\n",
 		},
+		"value types": {
+			flags: func() Flags {
+				flags := flag.NewFlagSet("test", flag.ContinueOnError)
+				flags.Var(StringMapValue(new(map[string]string)), "env", "An environment variable")
+				flags.Var(EnumValue(new(string), "debug", "info"), "level", "The log level")
+				flags.Var(ByteSizeValue(new(uint64)), "max-size", "The maximum size")
+
+				return flags
+			}(),
+			want: "\nOptions:\n\n" +
+				"\t-env key=value\tAn environment variable\n" +
+				"\t-level string \tThe log level (one of: debug, info)\n" +
+				"\t-max-size size\tThe maximum size\n",
+		},
+		"reflectable flags with required and env annotations": {
+			flags: &reflectableFlags{
+				flags: []mockReflectFlag{
+					{
+						Name:        "token",
+						Usage:       "The API token",
+						Value:       "string",
+						Annotations: map[string][]string{annotationRequired: {"true"}, annotationEnv: {"API_TOKEN"}},
+					},
+					{
+						Name:        "region",
+						Usage:       "The region",
+						DefValue:    "us",
+						Value:       "string",
+						Annotations: map[string][]string{annotationEnv: {"REGION"}},
+					},
+				},
+			},
+			want: "\nOptions:\n\n" +
+				"\t--token string \tThe API token (required) (env $API_TOKEN)\n" +
+				"\t--region string\tThe region (default \"us\") (env $REGION)\n",
+		},
 	} {
 		t.Run(testName, func(t *testing.T) {
 			var buf bytes.Buffer
-			testData.flags.SetOutput(&buf)
 
-			app := NewSingleCommandApp(testAppInfo, testNoOpExecutor, nil, io.Discard, io.Discard)
+			app := NewSingleCommandApp(testAppInfo, testNoOpExecutor, nil, io.Discard, &buf)
 			app.printFlagDefaults(testData.flags)
 
 			got := buf.String()
@@ -211,6 +263,370 @@ func TestPrintFlagDefaults(t *testing.T) {
 	}
 }
 
+func TestMarkFlagHidden(t *testing.T) {
+	for testName, testData := range map[string]struct {
+		flags   func() Flags
+		name    string
+		wantErr bool
+	}{
+		"defined flag": {
+			flags: func() Flags {
+				flags := flag.NewFlagSet("test", flag.ContinueOnError)
+				flags.Bool("legacy", false, "A legacy flag")
+				return flags
+			},
+			name: "legacy",
+		},
+		"undefined flag": {
+			flags: func() Flags {
+				return flag.NewFlagSet("test", flag.ContinueOnError)
+			},
+			name:    "legacy",
+			wantErr: true,
+		},
+		"unsupported flags": {
+			flags: func() Flags {
+				flags := bogusFlags("test")
+				return &flags
+			},
+			name:    "legacy",
+			wantErr: true,
+		},
+	} {
+		t.Run(testName, func(t *testing.T) {
+			flags := testData.flags()
+
+			err := MarkFlagHidden(flags, testData.name)
+			if (err != nil) != testData.wantErr {
+				t.Fatalf("MarkFlagHidden returned error %v, wanted error: %v", err, testData.wantErr)
+			}
+
+			if testData.wantErr {
+				return
+			}
+
+			visitFlags(flags, func(f flagInfo) {
+				if f.name == testData.name && !f.hidden {
+					t.Errorf("flag %q wasn't hidden", f.name)
+				}
+			})
+		})
+	}
+}
+
+func TestMarkFlagHidden_PreservesValueBehavior(t *testing.T) {
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+
+	var legacy bool
+	flags.BoolVar(&legacy, "legacy", false, "A legacy flag")
+	flags.Duration("timeout", 0, "A timeout")
+
+	_ = MarkFlagHidden(flags, "legacy")
+	_ = MarkFlagHidden(flags, "timeout")
+
+	if err := flags.Parse([]string{"-legacy", "-timeout", "1s"}); err != nil {
+		t.Fatalf("flags.Parse returned error: %v", err)
+	}
+
+	if !legacy {
+		t.Error("hidden bool flag wasn't set without an explicit value")
+	}
+
+	if got := flags.Lookup("timeout").Value.(flag.Getter).Get(); got != time.Second {
+		t.Errorf("hidden flag Get gave %v, want %v", got, time.Second)
+	}
+
+	visitFlags(flags, func(f flagInfo) {
+		if f.name == "timeout" && f.typeName != "duration" {
+			t.Errorf("hidden flag gave type name %q, want %q", f.typeName, "duration")
+		}
+	})
+
+	// Make sure the standard library's own defaults printing doesn't panic
+	flags.SetOutput(io.Discard)
+	flags.PrintDefaults()
+}
+
+func TestMarkFlagHidden_UnwrapsValue(t *testing.T) {
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+
+	var level int
+	value := CountValue(&level)
+	flags.Var(value, "level", "A level")
+
+	_ = MarkFlagHidden(flags, "level")
+
+	wrapped := flags.Lookup("level").Value
+
+	if typed, ok := wrapped.(Value); !ok || typed.Type() != "count" {
+		t.Errorf("hidden flag value %T doesn't forward its type", wrapped)
+	}
+
+	unwrapper, ok := wrapped.(interface{ Unwrap() flag.Value })
+	if !ok {
+		t.Fatalf("hidden flag value %T can't be unwrapped", wrapped)
+	}
+
+	if got := unwrapper.Unwrap(); got != value {
+		t.Errorf("hidden flag value unwrapped to %v, want %v", got, value)
+	}
+}
+
+func TestMarkFlagDeprecated(t *testing.T) {
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags.String("old", "", "An old flag")
+	flags.String("new", "", "A new flag")
+
+	deprecation := Deprecation{Replacement: "new", Message: "a message"}
+
+	if err := MarkFlagDeprecated(flags, "old", deprecation); err != nil {
+		t.Fatalf("MarkFlagDeprecated returned error: %v", err)
+	}
+
+	if err := MarkFlagDeprecated(flags, "undefined", deprecation); err == nil {
+		t.Error("MarkFlagDeprecated returned nil error for undefined flag")
+	}
+
+	_ = flags.Parse([]string{"-old", "val", "-new", "val"})
+
+	got := deprecatedFlagNotices(flags)
+	want := []string{"'-old' is deprecated, use '-new': a message"}
+
+	if len(got) != len(want) || got[0] != want[0] {
+		t.Errorf("deprecatedFlagNotices gave %q, want %q", got, want)
+	}
+}
+
+func TestMarkFlagRequired(t *testing.T) {
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags.String("token", "", "The API token")
+
+	if err := MarkFlagRequired(flags, "token"); err != nil {
+		t.Fatalf("MarkFlagRequired returned error: %v", err)
+	}
+
+	if err := MarkFlagRequired(flags, "undefined"); err == nil {
+		t.Error("MarkFlagRequired returned nil error for undefined flag")
+	}
+
+	_ = flags.Parse(nil)
+
+	wantErr := "flag '-token' is required"
+	if _, err := resolveFlags(flags, os.LookupEnv); err == nil || err.Error() != wantErr {
+		t.Errorf("resolveFlags gave %v, want %q", err, wantErr)
+	}
+
+	_ = flags.Parse([]string{"-token", "abc"})
+
+	if _, err := resolveFlags(flags, os.LookupEnv); err != nil {
+		t.Errorf("resolveFlags returned error: %v", err)
+	}
+}
+
+func TestSetFlagEnv(t *testing.T) {
+	t.Setenv("LIEUT_TEST_TOKEN", "from-env")
+	t.Setenv("LIEUT_TEST_RETRIES", "lots")
+
+	newFlags := func() (*flag.FlagSet, *string) {
+		flags := flag.NewFlagSet("test", flag.ContinueOnError)
+		token := flags.String("token", "", "The API token")
+		flags.Int("retries", 0, "The number of retries")
+
+		if err := SetFlagEnv(flags, "token", "LIEUT_TEST_TOKEN"); err != nil {
+			t.Fatalf("SetFlagEnv returned error: %v", err)
+		}
+
+		_ = MarkFlagRequired(flags, "token")
+
+		return flags, token
+	}
+
+	flags, token := newFlags()
+	_ = flags.Parse(nil)
+
+	if _, err := resolveFlags(flags, os.LookupEnv); err != nil {
+		t.Errorf("resolveFlags returned error: %v", err)
+	}
+
+	if *token != "from-env" {
+		t.Errorf("resolveFlags set token %q, want %q", *token, "from-env")
+	}
+
+	flags, token = newFlags()
+	_ = flags.Parse([]string{"-token", "from-flag"})
+
+	if _, err := resolveFlags(flags, os.LookupEnv); err != nil || *token != "from-flag" {
+		t.Errorf("resolveFlags gave token %q with error %v, want %q", *token, err, "from-flag")
+	}
+
+	flags, _ = newFlags()
+	_ = SetFlagEnv(flags, "retries", "LIEUT_TEST_RETRIES")
+	_ = flags.Parse(nil)
+
+	if _, err := resolveFlags(flags, os.LookupEnv); err == nil {
+		t.Error("resolveFlags returned nil error for an invalid environment value")
+	}
+
+	if err := SetFlagEnv(flags, "undefined", "UNDEFINED"); err == nil {
+		t.Error("SetFlagEnv returned nil error for undefined flag")
+	}
+}
+
+func TestMarkFlagNegatable(t *testing.T) {
+	t.Setenv("LIEUT_TEST_COLOR", "true")
+
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+
+	color := flags.Bool("color", true, "Colorize the output")
+	flags.String("output", "", "The output file")
+	flags.Bool("force", false, "Force it")
+	flags.Bool("no-force", false, "Don't force it")
+
+	if err := MarkFlagNegatable(flags, "color"); err != nil {
+		t.Fatalf("MarkFlagNegatable returned error: %v", err)
+	}
+
+	_ = SetFlagEnv(flags, "color", "LIEUT_TEST_COLOR")
+
+	for testName, testData := range map[string]struct {
+		flags Flags
+		name  string
+	}{
+		"undefined flag":       {flags: flags, name: "undefined"},
+		"non-boolean flag":     {flags: flags, name: "output"},
+		"negated form defined": {flags: flags, name: "force"},
+		"unsupported flags":    {flags: &verboseBogusFlags{}, name: "color"},
+	} {
+		t.Run(testName, func(t *testing.T) {
+			if err := MarkFlagNegatable(testData.flags, testData.name); err == nil {
+				t.Error("MarkFlagNegatable returned nil error")
+			}
+		})
+	}
+
+	if err := flags.Parse([]string{"-no-color"}); err != nil {
+		t.Fatalf("flags.Parse returned error: %v", err)
+	}
+
+	if _, err := resolveFlags(flags, os.LookupEnv); err != nil {
+		t.Fatalf("resolveFlags returned error: %v", err)
+	}
+
+	if *color {
+		t.Error("negated form didn't set the flag to false")
+	}
+
+	var set []string
+	visitSetFlags(flags, func(f flagInfo) {
+		set = append(set, f.name)
+	})
+
+	if want := []string{"color"}; !reflect.DeepEqual(set, want) {
+		t.Errorf("visitSetFlags visited %q, want %q", set, want)
+	}
+
+	if err := flags.Parse([]string{"-no-color=false"}); err != nil || !*color {
+		t.Errorf("flags.Parse gave color %v with error %v, want %v", *color, err, true)
+	}
+
+	var buf bytes.Buffer
+
+	app := NewSingleCommandApp(testAppInfo, testNoOpExecutor, nil, io.Discard, &buf)
+	app.printFlagDefaults(flags)
+
+	want := "\nOptions:\n\n" +
+		"\t-[no-]color   \tColorize the output (default true) (env $LIEUT_TEST_COLOR)\n" +
+		"\t-force        \tForce it\n" +
+		"\t-no-force     \tDon't force it\n" +
+		"\t-output string\tThe output file\n"
+
+	if got := buf.String(); got != want {
+		t.Errorf("printFlagDefaults gave %q, want %q", got, want)
+	}
+}
+
+func TestVisitSetFlags(t *testing.T) {
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags.String("set", "", "A set flag")
+	flags.String("unset", "", "An unset flag")
+
+	_ = flags.Parse([]string{"-set", "val"})
+
+	var visited []string
+	result := visitSetFlags(flags, func(f flagInfo) {
+		visited = append(visited, f.name)
+	})
+
+	if !result {
+		t.Error("visitSetFlags returned false, want true")
+	}
+
+	if len(visited) != 1 || visited[0] != "set" {
+		t.Errorf("visitSetFlags visited %q, want %q", visited, []string{"set"})
+	}
+
+	if result := visitSetFlags(&reflectableFlags{}, func(f flagInfo) {}); result {
+		t.Error("visitSetFlags returned true for flags without a Visit method, want false")
+	}
+}
+
+func TestSetFlagShorthand(t *testing.T) {
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+
+	var verbose bool
+	flags.BoolVar(&verbose, "verbose", false, "Enable verbose output")
+	flags.String("output", "", "The output file")
+
+	if err := SetFlagShorthand(flags, "verbose", "v"); err != nil {
+		t.Fatalf("SetFlagShorthand returned error: %v", err)
+	}
+
+	for testName, testData := range map[string]struct {
+		flags     Flags
+		name      string
+		shorthand string
+	}{
+		"multi-character shorthand": {flags: flags, name: "output", shorthand: "ou"},
+		"shorthand in use":          {flags: flags, name: "output", shorthand: "v"},
+		"undefined flag":            {flags: flags, name: "undefined", shorthand: "u"},
+		"unsupported flags":         {flags: &verboseBogusFlags{}, name: "output", shorthand: "o"},
+	} {
+		t.Run(testName, func(t *testing.T) {
+			if err := SetFlagShorthand(testData.flags, testData.name, testData.shorthand); err == nil {
+				t.Error("SetFlagShorthand returned nil error")
+			}
+		})
+	}
+
+	_ = MarkFlagDeprecated(flags, "verbose", Deprecation{})
+
+	if err := flags.Parse([]string{"-v"}); err != nil {
+		t.Fatalf("flags.Parse returned error: %v", err)
+	}
+
+	if !verbose {
+		t.Error("shorthand didn't set the flag's value")
+	}
+
+	if got, want := deprecatedFlagNotices(flags), "'--verbose' is deprecated"; len(got) != 1 || got[0] != want {
+		t.Errorf("deprecatedFlagNotices gave %q, want %q", got, []string{want})
+	}
+
+	var buf bytes.Buffer
+
+	app := NewSingleCommandApp(testAppInfo, testNoOpExecutor, nil, io.Discard, &buf)
+	app.printFlagDefaults(flags)
+
+	want := "\nOptions:\n\n" +
+		"\t    --output string\tThe output file\n" +
+		"\t-v, --verbose      \tEnable verbose output (deprecated)\n"
+
+	if got := buf.String(); got != want {
+		t.Errorf("printFlagDefaults gave %q, want %q", got, want)
+	}
+}
+
 func TestFormatFlagName(t *testing.T) {
 	for testName, testData := range map[string]struct {
 		f             flagInfo
@@ -274,6 +690,22 @@ func TestVisitFlags(t *testing.T) {
 				{name: "output", shorthand: "o", usage: "Output file", typeName: "string"},
 			},
 		},
+		"deprecations": {
+			flags: &reflectableFlags{
+				flags: []mockReflectFlag{
+					{
+						Name:        "annotated",
+						Annotations: map[string][]string{annotationDeprecated: {"new", "a message"}},
+					},
+					{Name: "native", Deprecated: "a native message"},
+				},
+			},
+			wantResult: true,
+			wantFlags: []flagInfo{
+				{name: "annotated", deprecation: &Deprecation{Replacement: "new", Message: "a message"}},
+				{name: "native", deprecation: &Deprecation{Message: "a native message"}},
+			},
+		},
 		"missing fields": {
 			flags: &minimalReflectableFlags{
 				flags: []minimalReflectFlag{{Name: "test"}},
@@ -305,7 +737,7 @@ func TestVisitFlags(t *testing.T) {
 			}
 
 			for i, want := range testData.wantFlags {
-				if visited[i] != want {
+				if !reflect.DeepEqual(visited[i], want) {
 					t.Errorf("flag %d: got %+v, want %+v", i, visited[i], want)
 				}
 			}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"runtime"
+	"strings"
 	"testing"
 
 	"github.com/spf13/pflag"
@@ -99,3 +100,446 @@ test vTest (%s/%s)
 		t.Errorf("app.PrintHelp gave %q, want %q", got, want)
 	}
 }
+
+func TestPFlag_ValueTypes(t *testing.T) {
+	var level string
+	var env map[string]string
+
+	flagSet := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flagSet.Var(lieut.EnumValue(&level, "debug", "info"), "level", "The log level")
+	flagSet.Var(lieut.StringMapValue(&env), "env", "An environment variable")
+
+	var buf bytes.Buffer
+	app := lieut.NewSingleCommandApp(testAppInfo, testNoOpExecutor, flagSet, &buf, &buf)
+
+	app.PrintHelp()
+
+	for _, want := range []string{
+		"--level string \tThe log level (one of: debug, info)\n",
+		"--env key=value\tAn environment variable\n",
+	} {
+		if got := buf.String(); !strings.Contains(got, want) {
+			t.Errorf("app.PrintHelp gave %q, want it to contain %q", got, want)
+		}
+	}
+
+	arguments := []string{"--level", "info", "--env", "a=1"}
+	if exitCode := app.Run(context.TODO(), arguments); exitCode != lieut.ExitCodeSuccess {
+		t.Errorf("app.Run gave %v, wanted %v", exitCode, lieut.ExitCodeSuccess)
+	}
+
+	if level != "info" || env["a"] != "1" {
+		t.Errorf("app.Run gave level %q and env %q", level, env)
+	}
+}
+
+func TestPFlag_NegatableFlags(t *testing.T) {
+	t.Setenv("LIEUT_TEST_COLOR", "true")
+
+	var color bool
+
+	flagSet := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flagSet.BoolVar(&color, "color", true, "Colorize the output")
+	flagSet.String("output", "", "The output file")
+
+	if err := lieut.MarkFlagNegatable(flagSet, "color"); err != nil {
+		t.Fatalf("MarkFlagNegatable returned error: %v", err)
+	}
+
+	if err := lieut.MarkFlagNegatable(flagSet, "output"); err == nil {
+		t.Error("MarkFlagNegatable returned nil error for a non-boolean flag")
+	}
+
+	_ = lieut.SetFlagEnv(flagSet, "color", "LIEUT_TEST_COLOR")
+
+	var buf bytes.Buffer
+	app := lieut.NewSingleCommandApp(testAppInfo, testNoOpExecutor, flagSet, &buf, &buf)
+
+	app.PrintHelp()
+
+	want := "\t    --[no-]color   \tColorize the output (default true) (env $LIEUT_TEST_COLOR)\n"
+	if !strings.Contains(buf.String(), want) {
+		t.Errorf("app.PrintHelp gave %q, want it to contain %q", buf.String(), want)
+	}
+
+	if strings.Contains(buf.String(), "--no-color") {
+		t.Errorf("app.PrintHelp gave %q, want the negated form to be hidden", buf.String())
+	}
+
+	if exitCode := app.Run(context.TODO(), []string{"--no-color"}); exitCode != lieut.ExitCodeSuccess {
+		t.Errorf("app.Run gave %v, wanted %v", exitCode, lieut.ExitCodeSuccess)
+	}
+
+	if color {
+		t.Error("app.Run gave color true, wanted false")
+	}
+}
+
+func TestPFlag_HiddenFlags(t *testing.T) {
+	wantFormat := `Usage: test testing
+
+A test
+
+Options:
+
+	    --my-flag string	My custom flag
+	    --version       	Display the application version
+	-h, --help          	Display the help message
+
+test vTest (%s/%s)
+`
+	want := fmt.Sprintf(wantFormat, runtime.GOOS, runtime.GOARCH)
+
+	flagSet := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flagSet.String("my-flag", "", "My custom flag")
+	flagSet.Bool("legacy", false, "A legacy flag")
+
+	if err := lieut.MarkFlagHidden(flagSet, "legacy"); err != nil {
+		t.Fatalf("MarkFlagHidden returned error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	app := lieut.NewSingleCommandApp(testAppInfo, testNoOpExecutor, flagSet, &buf, &buf)
+
+	app.PrintHelp()
+
+	got := buf.String()
+
+	if got != want {
+		t.Errorf("app.PrintHelp gave %q, want %q", got, want)
+	}
+
+	if exitCode := app.Run(context.TODO(), []string{"--legacy"}); exitCode != lieut.ExitCodeSuccess {
+		t.Errorf("app.Run gave %v, wanted %v", exitCode, lieut.ExitCodeSuccess)
+	}
+}
+
+func TestPFlag_DeprecatedFlags(t *testing.T) {
+	flagSet := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flagSet.String("new-flag", "", "A new flag")
+	flagSet.String("old-flag", "", "An old flag")
+
+	err := lieut.MarkFlagDeprecated(flagSet, "old-flag", lieut.Deprecation{Replacement: "new-flag"})
+	if err != nil {
+		t.Fatalf("MarkFlagDeprecated returned error: %v", err)
+	}
+
+	var out, errOut bytes.Buffer
+	app := lieut.NewSingleCommandApp(testAppInfo, testNoOpExecutor, flagSet, &out, &errOut)
+
+	if exitCode := app.Run(context.TODO(), []string{"--old-flag", "val"}); exitCode != lieut.ExitCodeSuccess {
+		t.Errorf("app.Run gave %v, wanted %v", exitCode, lieut.ExitCodeSuccess)
+	}
+
+	want := "Warning: '--old-flag' is deprecated, use '--new-flag'\n"
+
+	if got := errOut.String(); got != want {
+		t.Errorf("app.Run gave errOut %q, want %q", got, want)
+	}
+}
+
+func TestPFlag_RequiredAndEnvFlags(t *testing.T) {
+	t.Setenv("LIEUT_TEST_REGION", "eu")
+
+	var token, region string
+
+	newApp := func(errOut io.Writer) *lieut.SingleCommandApp {
+		flagSet := pflag.NewFlagSet("test", pflag.ContinueOnError)
+		flagSet.StringVar(&token, "token", "", "The API token")
+		flagSet.StringVar(&region, "region", "us", "The region")
+
+		if err := lieut.MarkFlagRequired(flagSet, "token"); err != nil {
+			t.Fatalf("MarkFlagRequired returned error: %v", err)
+		}
+
+		if err := lieut.SetFlagEnv(flagSet, "region", "LIEUT_TEST_REGION"); err != nil {
+			t.Fatalf("SetFlagEnv returned error: %v", err)
+		}
+
+		return lieut.NewSingleCommandApp(testAppInfo, testNoOpExecutor, flagSet, io.Discard, errOut)
+	}
+
+	var errOut bytes.Buffer
+
+	exitCode := newApp(&errOut).Run(context.TODO(), []string{"--region", "ap"})
+	if exitCode != lieut.ExitCodeUsageError {
+		t.Errorf("app.Run gave %v, wanted %v", exitCode, lieut.ExitCodeUsageError)
+	}
+
+	if want := "Error: flag '--token' is required\n"; !bytes.HasPrefix(errOut.Bytes(), []byte(want)) {
+		t.Errorf("app.Run gave errOut %q, want prefix %q", errOut.String(), want)
+	}
+
+	exitCode = newApp(io.Discard).Run(context.TODO(), []string{"--token", "abc"})
+	if exitCode != lieut.ExitCodeSuccess {
+		t.Errorf("app.Run gave %v, wanted %v", exitCode, lieut.ExitCodeSuccess)
+	}
+
+	if token != "abc" || region != "eu" {
+		t.Errorf("app.Run gave token %q and region %q, wanted %q and %q", token, region, "abc", "eu")
+	}
+}
+
+func TestPFlag_FlagStates(t *testing.T) {
+	flagSet := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flagSet.StringP("timezone", "t", "UTC", "The timezone")
+	flagSet.Bool("seconds", false, "Include seconds")
+
+	var timezone, seconds lieut.FlagState
+
+	executor := func(ctx context.Context, arguments []string) error {
+		timezone, _ = lieut.FlagStateFromContext(ctx, "timezone")
+		seconds, _ = lieut.FlagStateFromContext(ctx, "seconds")
+		return nil
+	}
+
+	app := lieut.NewSingleCommandApp(testAppInfo, executor, flagSet, io.Discard, io.Discard)
+
+	if exitCode := app.Run(context.TODO(), []string{"-t", "UTC"}); exitCode != lieut.ExitCodeSuccess {
+		t.Fatalf("app.Run gave %v, wanted %v", exitCode, lieut.ExitCodeSuccess)
+	}
+
+	want := lieut.FlagState{Name: "timezone", Value: "UTC", Source: lieut.FlagSourceCommandLine}
+	if timezone != want {
+		t.Errorf("FlagStateFromContext gave %+v, want %+v", timezone, want)
+	}
+
+	if want := (lieut.FlagState{Name: "seconds", Value: "false", Source: lieut.FlagSourceDefault}); seconds != want {
+		t.Errorf("FlagStateFromContext gave %+v, want %+v", seconds, want)
+	}
+}
+
+func TestPFlag_FlagStatesRepeated(t *testing.T) {
+	var region lieut.FlagState
+	executor := func(ctx context.Context, arguments []string) error {
+		region, _ = lieut.FlagStateFromContext(ctx, "region")
+		return nil
+	}
+
+	app, err := lieut.NewSingleCommandAppFunc(testAppInfo, func() (lieut.Flags, lieut.Executor, error) {
+		flagSet := pflag.NewFlagSet("test", pflag.ContinueOnError)
+		flagSet.String("region", "us", "The region")
+		flagSet.String("token", "", "The token")
+
+		_ = lieut.SetFlagEnv(flagSet, "region", "REGION")
+		_ = lieut.MarkFlagRequired(flagSet, "token")
+
+		return flagSet, executor, nil
+	}, io.Discard, io.Discard)
+	if err != nil {
+		t.Fatalf("NewSingleCommandAppFunc returned error: %v", err)
+	}
+
+	for _, run := range []struct {
+		arguments []string
+		env       map[string]string
+
+		wantExitCode int
+		wantRegion   lieut.FlagState
+	}{
+		{
+			arguments: []string{"--region=eu", "--token=a"},
+			env:       map[string]string{"REGION": "ap"},
+
+			wantExitCode: lieut.ExitCodeSuccess,
+			wantRegion:   lieut.FlagState{Name: "region", Value: "eu", Source: lieut.FlagSourceCommandLine},
+		},
+		{
+			arguments: []string{"--token=b"},
+			env:       map[string]string{"REGION": "ap"},
+
+			wantExitCode: lieut.ExitCodeSuccess,
+			wantRegion:   lieut.FlagState{Name: "region", Value: "ap", Source: lieut.FlagSourceEnvironment},
+		},
+		{
+			arguments: []string{"--token=c"},
+
+			wantExitCode: lieut.ExitCodeSuccess,
+			wantRegion:   lieut.FlagState{Name: "region", Value: "us", Source: lieut.FlagSourceDefault},
+		},
+		{
+			arguments: []string{"--region=eu"},
+
+			wantExitCode: lieut.ExitCodeUsageError,
+		},
+	} {
+		region = lieut.FlagState{}
+
+		ctx := lieut.WithEnvironment(context.TODO(), lieut.Environment{
+			LookupEnv: func(key string) (string, bool) {
+				value, ok := run.env[key]
+				return value, ok
+			},
+		})
+
+		if exitCode := app.Run(ctx, run.arguments); exitCode != run.wantExitCode {
+			t.Errorf("app.Run(%q) gave %v, wanted %v", run.arguments, exitCode, run.wantExitCode)
+		}
+
+		if region != run.wantRegion {
+			t.Errorf("app.Run(%q) gave region %+v, wanted %+v", run.arguments, region, run.wantRegion)
+		}
+	}
+}
+
+func TestPFlag_GlobalFlagsAfterCommandName(t *testing.T) {
+	for testName, testData := range map[string]struct {
+		args []string
+
+		wantedExitCode int
+		wantedVerbose  bool
+		wantedErrOut   string
+	}{
+		"long global flag": {
+			args: []string{"deploy", "--verbose"},
+
+			wantedExitCode: lieut.ExitCodeSuccess,
+			wantedVerbose:  true,
+		},
+		"shorthand global flag": {
+			args: []string{"deploy", "-v", "--force"},
+
+			wantedExitCode: lieut.ExitCodeSuccess,
+			wantedVerbose:  true,
+		},
+		"help shorthand": {
+			args: []string{"deploy", "-h"},
+
+			wantedExitCode: lieut.ExitCodeSuccess,
+			wantedErrOut: fmt.Sprintf(`Usage: test deploy [arguments ...]
+
+Options:
+
+	    --force  	Force the deploy
+	-v, --verbose	Enable verbose output
+	-h, --help   	Display the help message
+
+test vTest (%s/%s)
+`, runtime.GOOS, runtime.GOARCH),
+		},
+	} {
+		t.Run(testName, func(t *testing.T) {
+			var verbose bool
+			flagSet := pflag.NewFlagSet("test", pflag.ContinueOnError)
+			flagSet.BoolVarP(&verbose, "verbose", "v", false, "Enable verbose output")
+
+			var out, errOut bytes.Buffer
+			app := lieut.NewMultiCommandApp(testAppInfo, flagSet, &out, &errOut)
+
+			commandFlagSet := pflag.NewFlagSet("deploy", pflag.ContinueOnError)
+			commandFlagSet.Bool("force", false, "Force the deploy")
+
+			err := app.SetCommand(lieut.CommandInfo{Name: "deploy"}, testNoOpExecutor, commandFlagSet)
+			if err != nil {
+				t.Fatalf("SetCommand returned error: %v", err)
+			}
+
+			exitCode := app.Run(context.TODO(), testData.args)
+
+			if exitCode != testData.wantedExitCode {
+				t.Errorf("app.Run gave %v, wanted %v", exitCode, testData.wantedExitCode)
+			}
+
+			if verbose != testData.wantedVerbose {
+				t.Errorf("app.Run gave verbose %v, wanted %v", verbose, testData.wantedVerbose)
+			}
+
+			if errOut.String() != testData.wantedErrOut {
+				t.Errorf("app.Run gave errOut %q, wanted %q", errOut.String(), testData.wantedErrOut)
+			}
+		})
+	}
+}
+
+func TestPFlag_GlobalFlagShorthandConflict(t *testing.T) {
+	flagSet := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flagSet.Bool("debug", false, "Enable debug output")
+	flagSet.BoolP("verbose", "v", false, "Enable verbose output")
+
+	app := lieut.NewMultiCommandApp(testAppInfo, flagSet, io.Discard, io.Discard)
+
+	commandFlagSet := pflag.NewFlagSet("deploy", pflag.ContinueOnError)
+	commandFlagSet.StringP("version-tag", "v", "", "The version to deploy")
+
+	err := app.SetCommand(lieut.CommandInfo{Name: "deploy"}, testNoOpExecutor, commandFlagSet)
+	if err == nil {
+		t.Error("SetCommand returned nil error, wanted a conflict error")
+	}
+
+	// None of the globals should be merged, including those without conflicts
+	if commandFlagSet.Lookup("debug") != nil {
+		t.Error("SetCommand partially merged the global flags")
+	}
+}
+
+func TestPFlag_CommandHelpShorthandInUse(t *testing.T) {
+	flagSet := pflag.NewFlagSet("test", pflag.ContinueOnError)
+
+	app := lieut.NewMultiCommandApp(testAppInfo, flagSet, io.Discard, io.Discard)
+
+	var host string
+	commandFlagSet := pflag.NewFlagSet("connect", pflag.ContinueOnError)
+	commandFlagSet.StringVarP(&host, "host", "h", "", "The host to connect to")
+
+	err := app.SetCommand(lieut.CommandInfo{Name: "connect"}, testNoOpExecutor, commandFlagSet)
+	if err != nil {
+		t.Fatalf("SetCommand returned error: %v", err)
+	}
+
+	if exitCode := app.Run(context.TODO(), []string{"connect", "-h", "example"}); exitCode != lieut.ExitCodeSuccess {
+		t.Errorf("app.Run gave %v, wanted %v", exitCode, lieut.ExitCodeSuccess)
+	}
+
+	if host != "example" {
+		t.Errorf("app.Run gave host %q, wanted %q", host, "example")
+	}
+}
+
+func TestPFlag_DefaultCommandGlobalFlagHelpShorthand(t *testing.T) {
+	var host string
+	flagSet := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flagSet.StringVarP(&host, "host", "h", "", "The host to connect to")
+
+	var errOut bytes.Buffer
+	app := lieut.NewMultiCommandApp(testAppInfo, flagSet, io.Discard, &errOut)
+
+	commandFlagSet := pflag.NewFlagSet("connect", pflag.ContinueOnError)
+
+	err := app.SetCommand(lieut.CommandInfo{Name: "connect"}, testNoOpExecutor, commandFlagSet)
+	if err != nil {
+		t.Fatalf("SetCommand returned error: %v", err)
+	}
+
+	app.SetDefaultCommand("connect")
+
+	if exitCode := app.Run(context.TODO(), []string{"-h", "example.com"}); exitCode != lieut.ExitCodeSuccess {
+		t.Errorf("app.Run gave %v, wanted %v (errOut %q)", exitCode, lieut.ExitCodeSuccess, errOut.String())
+	}
+
+	if host != "example.com" {
+		t.Errorf("app.Run gave host %q, wanted %q", host, "example.com")
+	}
+}
+
+func TestPFlag_GlobalFlagHelpShorthand(t *testing.T) {
+	var host string
+	flagSet := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flagSet.StringVarP(&host, "host", "h", "", "The host to connect to")
+
+	app := lieut.NewMultiCommandApp(testAppInfo, flagSet, io.Discard, io.Discard)
+
+	commandFlagSet := pflag.NewFlagSet("connect", pflag.ContinueOnError)
+
+	err := app.SetCommand(lieut.CommandInfo{Name: "connect"}, testNoOpExecutor, commandFlagSet)
+	if err != nil {
+		t.Fatalf("SetCommand returned error: %v", err)
+	}
+
+	if exitCode := app.Run(context.TODO(), []string{"connect", "-h", "example"}); exitCode != lieut.ExitCodeSuccess {
+		t.Errorf("app.Run gave %v, wanted %v", exitCode, lieut.ExitCodeSuccess)
+	}
+
+	if host != "example" {
+		t.Errorf("app.Run gave host %q, wanted %q", host, "example")
+	}
+}
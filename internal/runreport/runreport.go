@@ -0,0 +1,54 @@
+// Copyright © 2026 Trevor N. Suarez (Rican7)
+
+// Package runreport provides a way for runs of lieut apps to report their
+// progress to the lieuttest package, without the reporting being a part of
+// lieut's API.
+package runreport
+
+import "context"
+
+// Reporter receives reports of the progress of a run.
+//
+// The Command type parameter is the type of the info of commands (which is
+// lieut.CommandInfo), as it can't be referenced here without an import cycle.
+type Reporter[Command any] struct {
+	// Resolved is called once the command to run is resolved, before its flags
+	// are parsed, with the command's info and full path, if set.
+	Resolved func(command Command, commandPath string)
+
+	// Executed is called with the error returned by the command's Executor,
+	// if set.
+	Executed func(err error)
+}
+
+type contextKey struct{}
+
+// WithReporter returns a copy of the given context that holds the given
+// reporter.
+func WithReporter[Command any](ctx context.Context, reporter *Reporter[Command]) context.Context {
+	return context.WithValue(ctx, contextKey{}, reporter)
+}
+
+// FromContext returns the reporter held by the given context, or nil if the
+// context doesn't hold one.
+func FromContext[Command any](ctx context.Context) *Reporter[Command] {
+	reporter, _ := ctx.Value(contextKey{}).(*Reporter[Command])
+
+	return reporter
+}
+
+// ReportResolved reports that the given command was resolved, if the reporter
+// is set and has a Resolved function.
+func (r *Reporter[Command]) ReportResolved(command Command, commandPath string) {
+	if r != nil && r.Resolved != nil {
+		r.Resolved(command, commandPath)
+	}
+}
+
+// ReportExecuted reports that the command was executed with the given result,
+// if the reporter is set and has an Executed function.
+func (r *Reporter[Command]) ReportExecuted(err error) {
+	if r != nil && r.Executed != nil {
+		r.Executed(err)
+	}
+}
@@ -18,6 +18,9 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
+
+	"github.com/Rican7/lieut/internal/runreport"
 )
 
 const (
@@ -27,6 +30,10 @@ const (
 	// DefaultParentCommandUsage defines the default usage string for commands
 	// that have sub-commands.
 	DefaultParentCommandUsage = "<command> [arguments ...]"
+
+	// DefaultCommandGroup defines the name of the group that commands without
+	// a group are listed under, when other commands have a group.
+	DefaultCommandGroup = "Other"
 )
 
 // Exit codes.
@@ -52,6 +59,45 @@ type CommandInfo struct {
 	Name    string
 	Summary string
 	Usage   string
+
+	// Hidden denotes that the command should be omitted from help output,
+	// while still being available to run.
+	Hidden bool
+
+	// Deprecated denotes that the command is deprecated, which results in a
+	// warning being displayed when it's used.
+	Deprecated *Deprecation
+
+	// Group is the name of the group that the command is listed under in help
+	// output, such as "Core commands". Commands without a group are listed
+	// under "Other" when any other command has a group.
+	Group string
+}
+
+// Deprecation describes the deprecation of a command or flag.
+type Deprecation struct {
+	// Replacement is the name of the command or flag that should be used
+	// instead, if any.
+	Replacement string
+
+	// Message provides any further details, such as when the deprecated
+	// command or flag will be removed.
+	Message string
+}
+
+// notice returns a standardized notice of the deprecation of the given name.
+func (d Deprecation) notice(name string, replacement string) string {
+	notice := fmt.Sprintf("'%s' is deprecated", name)
+
+	if replacement != "" {
+		notice += fmt.Sprintf(", use '%s'", replacement)
+	}
+
+	if d.Message != "" {
+		notice += ": " + d.Message
+	}
+
+	return notice
 }
 
 type command struct {
@@ -59,6 +105,8 @@ type command struct {
 	Executor
 
 	flags *flagSet // Flags for each command
+
+	build func() (Flags, Executor, error) // Builds a new instance per run, if set
 }
 
 // AppInfo describes information about an app.
@@ -77,9 +125,25 @@ type app struct {
 
 	out    io.Writer
 	errOut io.Writer
+	in     io.Reader // Defaults to os.Stdin, if nil
 
 	init        func() error
 	helpPrinter func() // Set per-run to display context-appropriate help
+
+	env         *Environment                     // Set per-run, if the run's environment is overridden
+	reporter    *runreport.Reporter[CommandInfo] // Set per-run, if the run is reported (such as by lieuttest)
+	commandPath string                           // Set per-run, once the command to run is resolved
+	command     *CommandInfo                     // Set per-run, once the command to run is resolved
+
+	parseMu *sync.Mutex // Serializes the parsing of flags among runs
+
+	errorFormat    ErrorFormat // Copied per-run, so that a flag can set it for a single run
+	errorFormatter ErrorFormatter
+
+	strictDeprecations bool
+	interspersed       bool
+	gnuStyleFlags      bool
+	responseFiles      bool
 }
 
 // SingleCommandApp is a runnable application that only has one command.
@@ -87,14 +151,27 @@ type SingleCommandApp struct {
 	app
 
 	exec Executor
+
+	build func() (Flags, Executor, error) // Builds a new instance per run, if set
 }
 
 // MultiCommandApp is a runnable application that has many commands.
 type MultiCommandApp struct {
 	app
 
+	buildFlags func() (Flags, error) // Builds new global flags per run, if set
+
 	commands     map[string]command
 	commandNames []string // Separate slice, to ensure consistent command order
+
+	groupOrder     []string
+	defaultCommand string
+
+	aliases    map[string]commandAlias
+	aliasNames []string // Separate slice, to ensure consistent alias order
+
+	plugins    bool
+	pluginDirs []string
 }
 
 // NewSingleCommandApp returns an initialized SingleCommandApp.
@@ -124,6 +201,8 @@ func NewSingleCommandApp(info AppInfo, exec Executor, flags Flags, out io.Writer
 
 			out:    out,
 			errOut: errOut,
+
+			parseMu: new(sync.Mutex),
 		},
 
 		exec: exec,
@@ -134,6 +213,34 @@ func NewSingleCommandApp(info AppInfo, exec Executor, flags Flags, out io.Writer
 	return app
 }
 
+// NewSingleCommandAppFunc returns an initialized SingleCommandApp, whose flags
+// and executor are built by the given function for each run.
+//
+// Building a new instance for each run means that the executor doesn't rely on
+// any state shared between runs, such as the values of flags, so that the app
+// can serve many concurrent runs. The function should return new flags each
+// time that it's called. The app's help is displayed from an instance built
+// when the app is initialized.
+//
+// It returns an error if the function returns an error when building the
+// initial instance.
+func NewSingleCommandAppFunc(
+	info AppInfo,
+	build func() (Flags, Executor, error),
+	out io.Writer,
+	errOut io.Writer,
+) (*SingleCommandApp, error) {
+	flags, exec, err := build()
+	if err != nil {
+		return nil, err
+	}
+
+	app := NewSingleCommandApp(info, exec, flags, out, errOut)
+	app.build = build
+
+	return app, nil
+}
+
 // NewMultiCommandApp returns an initialized MultiCommandApp.
 //
 // The provided flags are global/shared among the app's commands.
@@ -163,6 +270,8 @@ func NewMultiCommandApp(info AppInfo, flags Flags, out io.Writer, errOut io.Writ
 
 			out:    out,
 			errOut: errOut,
+
+			parseMu: new(sync.Mutex),
 		},
 
 		commands: make(map[string]command),
@@ -173,10 +282,41 @@ func NewMultiCommandApp(info AppInfo, flags Flags, out io.Writer, errOut io.Writ
 	return app
 }
 
+// NewMultiCommandAppFunc returns an initialized MultiCommandApp, whose global
+// flags are built by the given function for each run.
+//
+// Building new global flags for each run means that their values aren't shared
+// between runs, so that the app can serve many concurrent runs. The function
+// should return new flags each time that it's called. The new global flags are
+// merged into the flags of commands that are built per run (see
+// SetCommandFunc), and executors can read their values from the context (see
+// FlagStateFromContext). Commands set with SetCommand keep the global flags
+// built when the app is initialized, which also display the app's help.
+//
+// It returns an error if the function returns an error when building the
+// initial flags.
+func NewMultiCommandAppFunc(
+	info AppInfo,
+	build func() (Flags, error),
+	out io.Writer,
+	errOut io.Writer,
+) (*MultiCommandApp, error) {
+	flags, err := build()
+	if err != nil {
+		return nil, err
+	}
+
+	app := NewMultiCommandApp(info, flags, out, errOut)
+	app.buildFlags = build
+
+	return app, nil
+}
+
 // SetCommand sets a command for the given info, executor, and flags.
 //
 // It returns an error if the provided flags have already been used for another
-// command (or for the globals).
+// command (or for the globals), or if the global flags can't be merged into the
+// provided flags due to a conflict.
 //
 // The provided flags should have ContinueOnError ErrorHandling, or else flag
 // parsing errors won't properly be displayed/handled.
@@ -197,6 +337,10 @@ func (a *MultiCommandApp) SetCommand(info CommandInfo, exec Executor, flags Flag
 
 	a.setupFlagSet(flagSet, false)
 
+	if err := a.mergeGlobalFlags(flagSet); err != nil {
+		return err
+	}
+
 	if _, hasCommand := a.commands[info.Name]; !hasCommand {
 		a.commandNames = append(a.commandNames, info.Name)
 	}
@@ -206,6 +350,58 @@ func (a *MultiCommandApp) SetCommand(info CommandInfo, exec Executor, flags Flag
 	return nil
 }
 
+// SetCommandFunc sets a command for the given info, whose flags and executor
+// are built by the given function for each run.
+//
+// Building a new instance for each run means that the executor doesn't rely on
+// any state shared between runs, such as the values of flags, so that the app
+// can serve many concurrent runs of the command. The function should return new
+// flags each time that it's called. The command's help is displayed from an
+// instance built when the command is set.
+//
+// The global flags are still shared by concurrent runs, unless they're also
+// built per run (see NewMultiCommandAppFunc).
+//
+// It returns an error if the function returns an error when building the
+// initial instance, or for any of the reasons that SetCommand does.
+func (a *MultiCommandApp) SetCommandFunc(info CommandInfo, build func() (Flags, Executor, error)) error {
+	flags, exec, err := build()
+	if err != nil {
+		return err
+	}
+
+	if err := a.SetCommand(info, exec, flags); err != nil {
+		return err
+	}
+
+	cmd := a.commands[info.Name]
+	cmd.build = build
+	a.commands[info.Name] = cmd
+
+	return nil
+}
+
+// SetGroupOrder sets the order in which command groups are listed in help
+// output.
+//
+// Groups that aren't in the given order are listed after those that are, in the
+// order that their commands were set, followed by the DefaultCommandGroup.
+func (a *MultiCommandApp) SetGroupOrder(groups ...string) {
+	a.groupOrder = append([]string(nil), groups...)
+}
+
+// SetDefaultCommand sets the name of the command to run when no command name is
+// provided, either because no arguments were provided or because only flags
+// were provided.
+//
+// The root help and version flags still display the root help and version,
+// rather than being passed to the default command. A plugin command (see
+// SetPlugins) named after any global flags is still run, rather than the
+// default command.
+func (a *MultiCommandApp) SetDefaultCommand(name string) {
+	a.defaultCommand = name
+}
+
 // CommandNames returns the names of the set commands.
 func (a *MultiCommandApp) CommandNames() []string {
 	names := make([]string, len(a.commandNames))
@@ -225,27 +421,94 @@ func (a *MultiCommandApp) CommandNames() []string {
 // If the init function or command Executor returns a StatusCodeError, then the
 // returned exit code will match that of the value returned by
 // StatusCodeError.StatusCode().
+//
+// Run may be called repeatedly, and concurrently, on the same app. The state of
+// each run (such as a request for help) is reset, and the parsing of flags is
+// serialized. However, flags that aren't built per run (see
+// NewSingleCommandAppFunc) keep their values between runs, and are shared by
+// concurrent runs.
 func (a *SingleCommandApp) Run(ctx context.Context, arguments []string) int {
+	// Run with a copy of the app, so that per-run state isn't shared
+	r := *a
+	r.useEnvironment(ctx)
+
+	return r.run(ctx, arguments)
+}
+
+func (a *SingleCommandApp) run(ctx context.Context, arguments []string) int {
 	a.helpPrinter = a.PrintHelp
 
 	if len(arguments) == 0 {
-		arguments = os.Args[1:]
+		arguments = a.processArguments()
+	}
+
+	run := runMetadata{
+		app:         a.info,
+		commandPath: a.info.Name,
+		arguments:   arguments,
 	}
 
-	if err := a.flags.Parse(arguments); err != nil {
+	a.reporter.ReportResolved(CommandInfo{}, run.commandPath)
+
+	arguments, err := a.prepareArguments(arguments)
+	if err != nil {
 		a.PrintUsageError(err)
 		return ExitCodeUsageError
 	}
 
-	if intercepted := a.intercept(a.flags); intercepted {
-		return ExitCodeSuccess
+	ctx, arguments, exitCode, ok := a.parse(ctx, arguments)
+	if !ok {
+		return exitCode
 	}
 
 	if err := a.initialize(); err != nil {
 		return a.handleError(err)
 	}
 
-	return a.execute(ctx, a.exec, a.flags.Args())
+	return a.execute(ctx, run, a.exec, arguments)
+}
+
+// parse parses the given arguments with the app's flags, while holding the
+// app's parse lock, and returns a context holding the states of the flags and
+// the remaining arguments.
+//
+// It returns an exit code and false if the run should stop.
+func (a *SingleCommandApp) parse(ctx context.Context, arguments []string) (context.Context, []string, int, bool) {
+	a.parseMu.Lock()
+	defer a.parseMu.Unlock()
+
+	if a.build != nil {
+		flags, exec, err := a.buildFlagSet(a.info.Name, a.build, true)
+		if err != nil {
+			return ctx, nil, a.handleError(err), false
+		}
+
+		a.flags, a.exec = flags, exec
+	}
+
+	if err := a.parseFlags(a.flags, arguments); err != nil {
+		a.PrintUsageError(err)
+		return ctx, nil, ExitCodeUsageError, false
+	}
+
+	if intercepted := a.intercept(a.flags); intercepted {
+		return ctx, nil, ExitCodeSuccess, false
+	}
+
+	if err := a.handleDeprecations(deprecatedFlagNotices(a.flags)); err != nil {
+		a.PrintUsageError(err)
+		return ctx, nil, ExitCodeUsageError, false
+	}
+
+	fromEnv, err := resolveFlags(a.flags, a.lookupEnv)
+	a.useErrorFormatFlag(a.flags)
+
+	if err != nil {
+		a.PrintUsageError(err)
+		return ctx, nil, ExitCodeUsageError, false
+	}
+
+	return withFlagStates(ctx, a.flags, fromEnv), a.flags.Args(), ExitCodeSuccess, true
 }
 
 // Run takes a context and arguments, runs the expected command, and returns an
@@ -258,9 +521,42 @@ func (a *SingleCommandApp) Run(ctx context.Context, arguments []string) int {
 // If the init function or command Executor returns a StatusCodeError, then the
 // returned exit code will match that of the value returned by
 // StatusCodeError.StatusCode().
+//
+// Run may be called repeatedly, and concurrently, on the same app. The state of
+// each run (such as a request for help) is reset, and the parsing of flags is
+// serialized. However, flags that aren't built per run (see
+// NewMultiCommandAppFunc and SetCommandFunc) keep their values between runs,
+// and are shared by concurrent runs.
 func (a *MultiCommandApp) Run(ctx context.Context, arguments []string) int {
+	// Run with a copy of the app, so that per-run state isn't shared
+	r := *a
+	r.useEnvironment(ctx)
+
+	return r.run(ctx, arguments)
+}
+
+func (a *MultiCommandApp) run(ctx context.Context, arguments []string) int {
+	a.helpPrinter = nil
+
 	if len(arguments) == 0 {
-		arguments = os.Args[1:]
+		arguments = a.processArguments()
+	}
+
+	run := runMetadata{app: a.info, arguments: arguments}
+
+	arguments, err := a.prepareArguments(arguments)
+	if err != nil {
+		a.PrintUsageError("", err)
+		return ExitCodeUsageError
+	}
+
+	if arguments, err = a.expandAliases(arguments); err != nil {
+		a.PrintUsageError("", err)
+		return ExitCodeUsageError
+	}
+
+	if a.shouldRunDefaultCommand(arguments) {
+		arguments = append([]string{a.defaultCommand}, arguments...)
 	}
 
 	if len(a.commands) == 0 || len(arguments) == 0 {
@@ -268,39 +564,177 @@ func (a *MultiCommandApp) Run(ctx context.Context, arguments []string) int {
 		return ExitCodeUsageError
 	}
 
-	flags := a.flags
 	commandName := arguments[0]
 
 	cmd, hasCommand := a.commands[commandName]
-	if !hasCommand && commandName[0] != '-' {
-		return a.printUnknownCommand(commandName)
+	if !hasCommand {
+		if exitCode, isPlugin := a.runPlugin(ctx, run, arguments); isPlugin {
+			return exitCode
+		}
+
+		if !isFlagArgument(commandName) {
+			return a.printUnknownCommand(commandName)
+		}
 	}
 
 	if hasCommand {
-		flags = cmd.flags
+		info := cmd.info
+
+		a.commandPath = a.fullCommandName(commandName)
+		a.command = &info
+		a.reporter.ReportResolved(info, a.commandPath)
 		arguments = arguments[1:]
 	}
 
-	if err := flags.Parse(arguments); err != nil {
+	ctx, cmd, arguments, exitCode, ok := a.parse(ctx, commandName, arguments)
+	if !ok {
+		return exitCode
+	}
+
+	if err := a.initialize(); err != nil {
+		return a.handleError(err)
+	}
+
+	run.command = &cmd.info
+	run.commandPath = a.fullCommandName(commandName)
+
+	return a.execute(ctx, run, cmd.Executor, arguments)
+}
+
+// parse parses the given arguments with the flags of the named command (or
+// the global flags, if there's no such command), while holding the app's parse
+// lock, and returns a context holding the states of the flags, the command to
+// run, and the remaining arguments.
+//
+// It returns an exit code and false if the run should stop.
+func (a *MultiCommandApp) parse(
+	ctx context.Context,
+	commandName string,
+	arguments []string,
+) (context.Context, command, []string, int, bool) {
+	a.parseMu.Lock()
+	defer a.parseMu.Unlock()
+
+	if err := a.buildGlobalFlags(); err != nil {
+		return ctx, command{}, nil, a.handleError(err), false
+	}
+
+	flags := a.flags
+
+	cmd, hasCommand := a.commands[commandName]
+	if hasCommand {
+		var err error
+		if cmd, err = a.forRun(cmd); err != nil {
+			return ctx, cmd, nil, a.handleError(err), false
+		}
+
+		flags = cmd.flags
+	}
+
+	if err := a.parseFlags(flags, arguments); err != nil {
 		a.PrintUsageError(commandName, err)
-		return ExitCodeUsageError
+		return ctx, cmd, nil, ExitCodeUsageError, false
 	}
 
 	if intercepted := a.intercept(flags, commandName); intercepted {
-		return ExitCodeSuccess
+		return ctx, cmd, nil, ExitCodeSuccess, false
 	}
 
 	if !hasCommand {
-		return a.printUnknownCommand(commandName)
+		return ctx, cmd, nil, a.printUnknownCommand(commandName), false
 	}
 
 	a.helpPrinter = func() { a.PrintHelp(commandName) }
 
-	if err := a.initialize(); err != nil {
-		return a.handleError(err)
+	var notices []string
+	if cmd.info.Deprecated != nil {
+		notices = append(notices, cmd.info.Deprecated.notice(commandName, cmd.info.Deprecated.Replacement))
+	}
+
+	notices = append(notices, deprecatedFlagNotices(flags)...)
+
+	if err := a.handleDeprecations(notices); err != nil {
+		a.PrintUsageError(commandName, err)
+		return ctx, cmd, nil, ExitCodeUsageError, false
 	}
 
-	return a.execute(ctx, cmd.Executor, cmd.flags.Args())
+	fromEnv, err := resolveFlags(flags, a.lookupEnv)
+	a.useErrorFormatFlag(flags)
+
+	if err != nil {
+		a.PrintUsageError(commandName, err)
+		return ctx, cmd, nil, ExitCodeUsageError, false
+	}
+
+	return withFlagStates(ctx, flags, fromEnv), cmd, flags.Args(), ExitCodeSuccess, true
+}
+
+// buildGlobalFlags builds new global flags for a single run, if the global
+// flags are built per run.
+func (a *MultiCommandApp) buildGlobalFlags() error {
+	if a.buildFlags == nil {
+		return nil
+	}
+
+	flags, _, err := a.buildFlagSet(a.info.Name, func() (Flags, Executor, error) {
+		flags, err := a.buildFlags()
+
+		return flags, nil, err
+	}, true)
+	if err != nil {
+		return err
+	}
+
+	a.flags = flags
+
+	return nil
+}
+
+// forRun returns the command to use for a single run, building a new instance
+// of the command if it's built per run.
+func (a *MultiCommandApp) forRun(cmd command) (command, error) {
+	if cmd.build == nil {
+		return cmd, nil
+	}
+
+	flags, executor, err := a.buildFlagSet(cmd.info.Name, cmd.build, false)
+	if err != nil {
+		return cmd, err
+	}
+
+	cmd.Executor = executor
+	cmd.flags = flags
+
+	return cmd, nil
+}
+
+// buildFlagSet builds a new instance of the named command with the given build
+// function, and sets up its flags.
+func (a *app) buildFlagSet(
+	name string,
+	build func() (Flags, Executor, error),
+	isRoot bool,
+) (*flagSet, Executor, error) {
+	flags, executor, err := build()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if flags == nil {
+		flags = createDefaultFlags(name)
+	}
+
+	flagSet := &flagSet{Flags: flags}
+
+	a.setupFlagSet(flagSet, isRoot)
+
+	if !isRoot {
+		if err := a.mergeGlobalFlags(flagSet); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return flagSet, executor, nil
 }
 
 // OnInit takes an init function that is then called after initialization and
@@ -309,6 +743,60 @@ func (a *app) OnInit(init func() error) {
 	a.init = init
 }
 
+// SetInput sets the input reader of the app, which is provided to executors via
+// their context (see Stdin).
+//
+// By default, the input reader is os.Stdin.
+func (a *app) SetInput(in io.Reader) {
+	a.in = in
+}
+
+// SetStrictDeprecations sets whether the use of deprecated commands or flags
+// should be treated as a usage error, rather than just displaying a warning.
+//
+// This is useful in environments where deprecations should be caught early,
+// such as in continuous integration.
+func (a *app) SetStrictDeprecations(strict bool) {
+	a.strictDeprecations = strict
+}
+
+// SetInterspersed sets whether flags may be interspersed with positional
+// arguments, such as in `app deploy prod --force`, for flags from the standard
+// library's flag package.
+//
+// By default, the standard library stops parsing flags at the first positional
+// argument. Flags from other packages (like spf13/pflag) that natively handle
+// interspersed flags are unaffected.
+func (a *app) SetInterspersed(interspersed bool) {
+	a.interspersed = interspersed
+}
+
+// SetGNUStyleFlags sets whether GNU-style flag arguments should be supported,
+// for flags from the standard library's flag package.
+//
+// When enabled, bundled single-character flags (`-vvf file`) and values attached
+// to single-character flags (`-ofile`, `-o=file`) are supported. Single-character
+// flags can be registered as shorthands of other flags via SetFlagShorthand, and
+// repeatable counting flags can be defined via CountValue.
+func (a *app) SetGNUStyleFlags(enabled bool) {
+	a.gnuStyleFlags = enabled
+}
+
+// SetResponseFiles sets whether response file arguments should be expanded,
+// such as for long lists of arguments that would otherwise exceed the limits of
+// a command line.
+//
+// When enabled, each `@path` argument is replaced with the arguments contained
+// in the file at the path, before the command and flags are parsed. The file's
+// contents are split into arguments in the style of a POSIX shell, supporting
+// quotes, backslash escapes, and `#` comments. Response files may refer to other
+// response files, up to a limited depth. An argument starting with `@@` is an
+// escape for a literal argument starting with `@`, and arguments after a `--`
+// terminator aren't expanded.
+func (a *app) SetResponseFiles(enabled bool) {
+	a.responseFiles = enabled
+}
+
 // PrintVersion prints the version to the app's standard output.
 func (a *app) PrintVersion() {
 	a.printVersion(false)
@@ -352,28 +840,59 @@ func (a *MultiCommandApp) PrintUsage(commandName string) {
 
 // PrintUsageError prints a standardized usage error to the app's error output.
 func (a *SingleCommandApp) PrintUsageError(err error) {
-	if err != nil && a.printError(err) {
+	displayed := a.displayError(ErrorInfo{
+		Err:         err,
+		Kind:        ErrorKindUsage,
+		CommandPath: a.info.Name,
+		ExitCode:    ExitCodeUsageError,
+		Hint:        usageHint(a.info.Name),
+	})
+
+	if displayed && a.isJSONErrorFormat() {
+		return
+	}
+
+	if displayed {
 		// Print a spacer line if an error was printed
 		fmt.Fprintln(a.errOut)
 	}
 
 	a.PrintUsage()
 
-	fmt.Fprintf(a.errOut, "\nRun '%s --help' for usage.\n", a.info.Name)
+	fmt.Fprintf(a.errOut, "\n%s\n", usageHint(a.info.Name))
 }
 
 // PrintUsageError prints a standardized usage error to the app's error output.
 func (a *MultiCommandApp) PrintUsageError(commandName string, err error) {
+	a.printUsageError(commandName, ErrorInfo{Err: err, Kind: ErrorKindUsage, ExitCode: ExitCodeUsageError})
+}
+
+// printUsageError prints a usage error of the given info, for the named
+// command, to the app's error output.
+func (a *MultiCommandApp) printUsageError(commandName string, e ErrorInfo) {
 	name := a.fullCommandName(commandName)
 
-	if err != nil && a.printError(err) {
+	e.CommandPath = name
+	e.Hint = usageHint(name)
+
+	if command, hasCommand := a.commands[commandName]; hasCommand {
+		e.Command = &command.info
+	}
+
+	displayed := a.displayError(e)
+
+	if displayed && a.isJSONErrorFormat() {
+		return
+	}
+
+	if displayed {
 		// Print a spacer line if an error was printed
 		fmt.Fprintln(a.errOut)
 	}
 
 	a.PrintUsage(commandName)
 
-	fmt.Fprintf(a.errOut, "\nRun '%s --help' for usage.\n", name)
+	fmt.Fprintf(a.errOut, "\n%s\n", usageHint(name))
 }
 
 func (a *app) intercept(flagSet *flagSet) bool {
@@ -403,6 +922,72 @@ func (a *MultiCommandApp) intercept(flagSet *flagSet, commandName string) bool {
 	return a.app.intercept(flagSet)
 }
 
+// prepareArguments prepares the given arguments according to the app's
+// configuration, before they're parsed.
+func (a *app) prepareArguments(arguments []string) ([]string, error) {
+	if a.responseFiles {
+		return expandResponseFiles(arguments, a.workDir())
+	}
+
+	return arguments, nil
+}
+
+// parseFlags parses the given arguments with the given flags, first preparing
+// the arguments according to the app's configuration.
+func (a *app) parseFlags(flagSet *flagSet, arguments []string) error {
+	// Reset any requests from a previous run
+	resetRunState(flagSet)
+	flagSet.requestedHelp = false
+	flagSet.requestedVersion = false
+
+	if flags, ok := stdFlags(flagSet); ok {
+		if a.gnuStyleFlags {
+			arguments = normalizeGNUArguments(flags, arguments, a.interspersed)
+		}
+
+		if a.interspersed {
+			arguments = intersperseArguments(flags, arguments)
+		}
+	}
+
+	err := flagSet.Parse(arguments)
+
+	// Apply the error format even if parsing failed, so that the error is
+	// displayed in the format
+	a.useErrorFormatFlag(flagSet)
+
+	return err
+}
+
+// handleDeprecations takes deprecation notices and either prints them as
+// warnings, or returns the first as an error if the app is strict.
+func (a *app) handleDeprecations(notices []string) error {
+	if len(notices) == 0 {
+		return nil
+	}
+
+	if a.strictDeprecations {
+		return errors.New(notices[0])
+	}
+
+	for _, notice := range notices {
+		if a.isJSONErrorFormat() {
+			// Keep the error output a stream of JSON objects
+			a.printJSONError(ErrorInfo{
+				Err:         errors.New(notice),
+				Kind:        ErrorKindDeprecation,
+				CommandPath: a.runCommandPath(),
+				ExitCode:    ExitCodeSuccess,
+			})
+			continue
+		}
+
+		fmt.Fprintf(a.errOut, "Warning: %s\n", notice)
+	}
+
+	return nil
+}
+
 func (a *app) initialize() error {
 	if a.init == nil {
 		return nil
@@ -411,11 +996,19 @@ func (a *app) initialize() error {
 	return a.init()
 }
 
-func (a *app) execute(ctx context.Context, exec Executor, arguments []string) int {
-	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
-	defer stop()
+func (a *app) execute(ctx context.Context, run runMetadata, exec Executor, arguments []string) int {
+	// Leave signal handling to the process, unless the environment is overridden
+	if a.env == nil {
+		var stop context.CancelFunc
+		ctx, stop = signal.NotifyContext(ctx, os.Interrupt)
+		defer stop()
+	}
+
+	ctx = a.withIO(ctx)
+	ctx = withRunMetadata(ctx, run)
 
 	err := exec(ctx, arguments)
+	a.reporter.ReportExecuted(err)
 	if err != nil && !errors.Is(err, context.Canceled) {
 		return a.handleError(err)
 	}
@@ -423,45 +1016,157 @@ func (a *app) execute(ctx context.Context, exec Executor, arguments []string) in
 	return ExitCodeSuccess
 }
 
-// printError takes an error, prints it with formatting, and then returns
-// whether or not any actual error message was printed.
+// printError takes an error, displays it, and then returns whether or not
+// anything was displayed.
 func (a *app) printError(err error) bool {
-	msg := err.Error()
-
-	if msg == "" {
-		// Return false to denote that no error was printed
-		return false
-	}
-
-	fmt.Fprintf(a.errOut, "Error: %s\n", msg)
-
-	return true
+	return a.displayError(ErrorInfo{
+		Err:         err,
+		Kind:        ErrorKindRuntime,
+		CommandPath: a.runCommandPath(),
+		Command:     a.command,
+		ExitCode:    ExitCodeError,
+	})
 }
 
 func (a *app) handleError(err error) int {
 	exitCode := ExitCodeError
+	isHelpRequested := errors.Is(err, ErrHelpRequested)
 
-	switch {
-	case errors.Is(err, ErrHelpRequested):
+	if isHelpRequested {
 		exitCode = ExitCodeUsageError
+	}
+
+	var statusErr StatusCodeError
+	if errors.As(err, &statusErr) {
+		exitCode = statusErr.StatusCode()
+	}
+
+	kind := ErrorKindRuntime
+	if isHelpRequested {
+		kind = ErrorKindHelpRequested
+	}
+
+	displayed := a.displayError(ErrorInfo{
+		Err:         err,
+		Kind:        kind,
+		CommandPath: a.runCommandPath(),
+		Command:     a.command,
+		ExitCode:    exitCode,
+	})
 
-		if a.printError(err) {
+	if isHelpRequested && !a.isJSONErrorFormat() {
+		if displayed {
 			fmt.Fprintln(a.errOut)
 		}
 
 		if a.helpPrinter != nil {
 			a.helpPrinter()
 		}
-	default:
-		a.printError(err)
 	}
 
-	var statusErr StatusCodeError
-	if errors.As(err, &statusErr) {
-		return statusErr.StatusCode()
+	return exitCode
+}
+
+// runCommandPath returns the full path of the command of the run, or the
+// app's name if no command has been resolved.
+func (a *app) runCommandPath() string {
+	if a.commandPath != "" {
+		return a.commandPath
 	}
 
-	return exitCode
+	return a.info.Name
+}
+
+// shouldRunDefaultCommand returns whether the given arguments should be run by
+// the default command, if one is set.
+func (a *MultiCommandApp) shouldRunDefaultCommand(arguments []string) bool {
+	if _, hasDefault := a.commands[a.defaultCommand]; !hasDefault {
+		return false
+	}
+
+	if len(arguments) > 0 && !isFlagArgument(arguments[0]) {
+		return false
+	}
+
+	// Let a command or plugin named after any global flags be run instead
+	if count := leadingFlagCount(a.flags.Flags, arguments); count < len(arguments) {
+		name := arguments[count]
+
+		if _, hasCommand := a.commands[name]; hasCommand {
+			return false
+		}
+
+		if _, isPlugin := a.findPlugin(name); isPlugin {
+			return false
+		}
+	}
+
+	flags := a.commands[a.defaultCommand].flags.Flags
+	takesValue := valueFlagNames(flags)
+	requestsHelp := helpFlagNames(flags)
+
+	// Let the root help and version flags be handled by the root
+	for i := 0; i < len(arguments); i++ {
+		argument := arguments[i]
+
+		if argument == argumentTerminator {
+			break
+		}
+
+		if !isFlagArgument(argument) {
+			continue
+		}
+
+		name, _, hasValue := strings.Cut(strings.TrimLeft(argument, "-"), "=")
+
+		if requestsHelp[name] || name == "version" {
+			return false
+		}
+
+		// Skip the flag's value, so that it isn't mistaken for a flag
+		if !hasValue && takesValue[name] {
+			i++
+		}
+	}
+
+	return true
+}
+
+// helpFlagNames returns the names and shorthands that request help from the
+// given flags, as the help shorthand may belong to another flag.
+func helpFlagNames(flags Flags) map[string]bool {
+	requestsHelp := map[string]bool{"help": true}
+
+	visitFlags(flags, func(f flagInfo) {
+		if f.name == "help" && f.shorthand != "" {
+			requestsHelp[f.shorthand] = true
+		}
+	})
+
+	// The standard library's flag package requests help for an undefined `-h`
+	if lookup, ok := flags.(lookupFlagger); ok && lookup.Lookup("h") == nil {
+		requestsHelp["h"] = true
+	}
+
+	return requestsHelp
+}
+
+// valueFlagNames returns the names and shorthands of the given flags that take
+// a value, rather than being boolean-like.
+func valueFlagNames(flags Flags) map[string]bool {
+	takesValue := make(map[string]bool)
+
+	visitFlags(flags, func(f flagInfo) {
+		if !f.boolean {
+			takesValue[f.name] = true
+
+			if f.shorthand != "" {
+				takesValue[f.shorthand] = true
+			}
+		}
+	})
+
+	return takesValue
 }
 
 func (a *MultiCommandApp) fullCommandName(commandName string) string {
@@ -490,7 +1195,11 @@ func (a *app) printVersion(toErr bool) {
 }
 
 func (a *MultiCommandApp) printUnknownCommand(commandName string) int {
-	a.PrintUsageError("", fmt.Errorf("unknown command '%s'", commandName))
+	a.printUsageError("", ErrorInfo{
+		Err:      fmt.Errorf("unknown command '%s'", commandName),
+		Kind:     ErrorKindUnknownCommand,
+		ExitCode: ExitCodeError,
+	})
 
 	return ExitCodeError
 }
@@ -527,22 +1236,117 @@ func (a *MultiCommandApp) printFullUsage(commandName string) {
 			fmt.Fprintln(a.errOut, a.info.Summary)
 		}
 
+		a.printCommands()
+		a.printAliases()
+		a.printPlugins()
+		a.printFlagDefaults(a.flags)
+	}
+}
+
+func (a *MultiCommandApp) printCommands() {
+	var visibleNames []string
+	isGrouped := false
+	for _, name := range a.commandNames {
+		command := a.commands[name]
+
+		if !command.info.Hidden {
+			visibleNames = append(visibleNames, name)
+			isGrouped = isGrouped || command.info.Group != ""
+		}
+	}
+
+	if len(visibleNames) == 0 {
+		return
+	}
+
+	maxNameLength := 0
+	for _, name := range visibleNames {
+		if len(name) > maxNameLength {
+			maxNameLength = len(name)
+		}
+	}
+
+	printCommand := func(command command) {
+		summary := command.info.Summary
+		if command.info.Deprecated != nil {
+			summary = strings.TrimSpace(summary + " (deprecated)")
+		}
+
+		fmt.Fprintf(a.errOut, "\t%-[1]*s\t%s\n", maxNameLength, command.info.Name, summary)
+	}
+
+	if !isGrouped {
 		fmt.Fprintf(a.errOut, "\nCommands:\n\n")
 
-		maxNameLength := 0
-		for _, name := range a.commandNames {
-			if len(name) > maxNameLength {
-				maxNameLength = len(name)
-			}
+		for _, name := range visibleNames {
+			printCommand(a.commands[name])
 		}
 
-		for _, name := range a.commandNames {
+		return
+	}
+
+	for _, group := range a.orderedGroups(visibleNames) {
+		fmt.Fprintf(a.errOut, "\n%s:\n\n", group)
+
+		for _, name := range visibleNames {
 			command := a.commands[name]
-			fmt.Fprintf(a.errOut, "\t%-[1]*s\t%s\n", maxNameLength, command.info.Name, command.info.Summary)
+
+			commandGroup := command.info.Group
+			if commandGroup == "" {
+				commandGroup = DefaultCommandGroup
+			}
+
+			if commandGroup == group {
+				printCommand(command)
+			}
 		}
+	}
+}
 
-		a.printFlagDefaults(a.flags)
+// orderedGroups returns the names of the groups of the given commands, in the
+// order that they should be listed.
+func (a *MultiCommandApp) orderedGroups(commandNames []string) []string {
+	hasGroup := make(map[string]bool)
+	var appearanceOrder []string
+	for _, name := range commandNames {
+		group := a.commands[name].info.Group
+		if group == "" {
+			group = DefaultCommandGroup
+		}
+
+		if !hasGroup[group] {
+			hasGroup[group] = true
+			appearanceOrder = append(appearanceOrder, group)
+		}
+	}
+
+	var groups []string
+	isListed := make(map[string]bool)
+	addGroup := func(group string) {
+		if hasGroup[group] && !isListed[group] {
+			isListed[group] = true
+			groups = append(groups, group)
+		}
+	}
+
+	for _, group := range a.groupOrder {
+		addGroup(group)
+	}
+
+	for _, group := range appearanceOrder {
+		if group != DefaultCommandGroup {
+			addGroup(group)
+		}
 	}
+
+	addGroup(DefaultCommandGroup)
+
+	return groups
+}
+
+// isFlagArgument returns whether the given argument looks like a flag.
+func isFlagArgument(argument string) bool {
+	return strings.HasPrefix(argument, "-")
 }
 
 func inferAppName() string {
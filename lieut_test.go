@@ -9,7 +9,10 @@ import (
 	"io"
 	"math/rand"
 	"os"
+	"path/filepath"
+	"reflect"
 	"runtime"
+	"strings"
 	"testing"
 	"time"
 )
@@ -418,6 +421,383 @@ test vTest (%s/%s)
 	}
 }
 
+func TestMultiCommandApp_PrintHelp_Hidden(t *testing.T) {
+	wantFormat := `Usage: test testing
+
+A test
+
+Commands:
+
+	visible	A visible command
+
+Options:
+
+	-testflag string	A test flag (default "testval")
+	-version        	Display the application version
+	-help           	Display the help message
+
+test vTest (%s/%s)
+`
+	want := fmt.Sprintf(wantFormat, runtime.GOOS, runtime.GOARCH)
+
+	var buf bytes.Buffer
+
+	flagSet := flag.NewFlagSet(testAppInfo.Name, flag.ContinueOnError)
+	flagSet.String("testflag", "testval", "A test flag")
+	flagSet.Bool("legacy", false, "A legacy flag")
+
+	if err := MarkFlagHidden(flagSet, "legacy"); err != nil {
+		t.Fatalf("MarkFlagHidden returned error: %v", err)
+	}
+
+	app := NewMultiCommandApp(testAppInfo, flagSet, &buf, &buf)
+
+	_ = app.SetCommand(CommandInfo{Name: "visible", Summary: "A visible command"}, testNoOpExecutor, nil)
+	_ = app.SetCommand(
+		CommandInfo{Name: "much-longer-debug", Summary: "A hidden command", Hidden: true},
+		testNoOpExecutor,
+		nil,
+	)
+
+	app.PrintHelp("")
+
+	got := buf.String()
+
+	if got != want {
+		t.Errorf("app.PrintHelp gave %q, want %q", got, want)
+	}
+}
+
+func TestMultiCommandApp_Run_Hidden(t *testing.T) {
+	flagSet := flag.NewFlagSet(testAppInfo.Name, flag.ContinueOnError)
+
+	var legacy bool
+	flagSet.BoolVar(&legacy, "legacy", false, "A legacy flag")
+
+	if err := MarkFlagHidden(flagSet, "legacy"); err != nil {
+		t.Fatalf("MarkFlagHidden returned error: %v", err)
+	}
+
+	app := NewMultiCommandApp(testAppInfo, flagSet, io.Discard, io.Discard)
+
+	ran := false
+	executor := func(ctx context.Context, arguments []string) error {
+		ran = true
+		return nil
+	}
+
+	_ = app.SetCommand(CommandInfo{Name: "debug", Hidden: true}, executor, nil)
+
+	if exitCode := app.Run(context.TODO(), []string{"debug", "--legacy"}); exitCode != ExitCodeSuccess {
+		t.Errorf("app.Run gave %v, wanted %v", exitCode, ExitCodeSuccess)
+	}
+
+	if !ran {
+		t.Error("app.Run didn't run hidden command")
+	}
+
+	if !legacy {
+		t.Error("app.Run didn't parse hidden flag")
+	}
+}
+
+func TestMultiCommandApp_PrintHelp_Deprecated(t *testing.T) {
+	wantFormat := `Usage: test testing
+
+A test
+
+Commands:
+
+	new	A new command
+	old	An old command (deprecated)
+
+Options:
+
+	-new string	A new flag
+	-old string	An old flag (deprecated)
+	-version   	Display the application version
+	-help      	Display the help message
+
+test vTest (%s/%s)
+`
+	want := fmt.Sprintf(wantFormat, runtime.GOOS, runtime.GOARCH)
+
+	var buf bytes.Buffer
+
+	flagSet := flag.NewFlagSet(testAppInfo.Name, flag.ContinueOnError)
+	flagSet.String("new", "", "A new flag")
+	flagSet.String("old", "", "An old flag")
+
+	if err := MarkFlagDeprecated(flagSet, "old", Deprecation{Replacement: "new"}); err != nil {
+		t.Fatalf("MarkFlagDeprecated returned error: %v", err)
+	}
+
+	app := NewMultiCommandApp(testAppInfo, flagSet, &buf, &buf)
+
+	_ = app.SetCommand(CommandInfo{Name: "new", Summary: "A new command"}, testNoOpExecutor, nil)
+	_ = app.SetCommand(
+		CommandInfo{Name: "old", Summary: "An old command", Deprecated: &Deprecation{Replacement: "new"}},
+		testNoOpExecutor,
+		nil,
+	)
+
+	app.PrintHelp("")
+
+	got := buf.String()
+
+	if got != want {
+		t.Errorf("app.PrintHelp gave %q, want %q", got, want)
+	}
+}
+
+func TestMultiCommandApp_Run_Deprecated(t *testing.T) {
+	for testName, testData := range map[string]struct {
+		strict bool
+		args   []string
+
+		wantedExitCode int
+		wantedErrOut   string
+	}{
+		"deprecated command": {
+			args: []string{"old"},
+
+			wantedExitCode: ExitCodeSuccess,
+			wantedErrOut:   "Warning: 'old' is deprecated, use 'new': going away soon\n",
+		},
+		"deprecated flag": {
+			args: []string{"new", "-old-flag", "val"},
+
+			wantedExitCode: ExitCodeSuccess,
+			wantedErrOut:   "Warning: '-old-flag' is deprecated, use '-new-flag'\n",
+		},
+		"deprecated command and flag": {
+			args: []string{"old", "-old-flag", "val"},
+
+			wantedExitCode: ExitCodeSuccess,
+			wantedErrOut: "Warning: 'old' is deprecated, use 'new': going away soon\n" +
+				"Warning: '-old-flag' is deprecated, use '-new-flag'\n",
+		},
+		"non-deprecated usage": {
+			args: []string{"new", "-new-flag", "val"},
+
+			wantedExitCode: ExitCodeSuccess,
+			wantedErrOut:   "",
+		},
+		"strict deprecated command": {
+			strict: true,
+			args:   []string{"old"},
+
+			wantedExitCode: ExitCodeUsageError,
+			wantedErrOut: "Error: 'old' is deprecated, use 'new': going away soon\n\n" +
+				"Usage: test old [arguments ...]\n\nRun 'test old --help' for usage.\n",
+		},
+		"strict deprecated flag": {
+			strict: true,
+			args:   []string{"new", "-old-flag", "val"},
+
+			wantedExitCode: ExitCodeUsageError,
+			wantedErrOut: "Error: '-old-flag' is deprecated, use '-new-flag'\n\n" +
+				"Usage: test new [arguments ...]\n\nRun 'test new --help' for usage.\n",
+		},
+	} {
+		t.Run(testName, func(t *testing.T) {
+			var out, errOut bytes.Buffer
+
+			flagSet := flag.NewFlagSet(testAppInfo.Name, flag.ContinueOnError)
+			flagSet.String("new-flag", "", "A new flag")
+			flagSet.String("old-flag", "", "An old flag")
+
+			_ = MarkFlagDeprecated(flagSet, "old-flag", Deprecation{Replacement: "new-flag"})
+
+			app := NewMultiCommandApp(testAppInfo, flagSet, &out, &errOut)
+			app.SetStrictDeprecations(testData.strict)
+
+			_ = app.SetCommand(CommandInfo{Name: "new"}, testNoOpExecutor, nil)
+			_ = app.SetCommand(
+				CommandInfo{Name: "old", Deprecated: &Deprecation{Replacement: "new", Message: "going away soon"}},
+				testNoOpExecutor,
+				nil,
+			)
+
+			exitCode := app.Run(context.TODO(), testData.args)
+
+			if exitCode != testData.wantedExitCode {
+				t.Errorf("app.Run gave %v, wanted %v", exitCode, testData.wantedExitCode)
+			}
+
+			if errOut.String() != testData.wantedErrOut {
+				t.Errorf("app.Run gave errOut %q, wanted %q", errOut.String(), testData.wantedErrOut)
+			}
+		})
+	}
+}
+
+func TestSingleCommandApp_Run_Deprecated(t *testing.T) {
+	for testName, testData := range map[string]struct {
+		strict bool
+
+		wantedExitCode int
+		wantedErrOut   string
+	}{
+		"warning": {
+			wantedExitCode: ExitCodeSuccess,
+			wantedErrOut:   "Warning: '-old' is deprecated\n",
+		},
+		"strict": {
+			strict: true,
+
+			wantedExitCode: ExitCodeUsageError,
+			wantedErrOut:   "Error: '-old' is deprecated\n\nUsage: test testing\n\nRun 'test --help' for usage.\n",
+		},
+	} {
+		t.Run(testName, func(t *testing.T) {
+			var out, errOut bytes.Buffer
+
+			flagSet := flag.NewFlagSet(testAppInfo.Name, flag.ContinueOnError)
+			flagSet.Bool("old", false, "An old flag")
+
+			_ = MarkFlagDeprecated(flagSet, "old", Deprecation{})
+
+			app := NewSingleCommandApp(testAppInfo, testNoOpExecutor, flagSet, &out, &errOut)
+			app.SetStrictDeprecations(testData.strict)
+
+			exitCode := app.Run(context.TODO(), []string{"-old"})
+
+			if exitCode != testData.wantedExitCode {
+				t.Errorf("app.Run gave %v, wanted %v", exitCode, testData.wantedExitCode)
+			}
+
+			if errOut.String() != testData.wantedErrOut {
+				t.Errorf("app.Run gave errOut %q, wanted %q", errOut.String(), testData.wantedErrOut)
+			}
+		})
+	}
+}
+
+func TestSingleCommandApp_Run_RequiredFlags(t *testing.T) {
+	for testName, testData := range map[string]struct {
+		arguments []string
+		env       string
+
+		wantedExitCode int
+		wantedErrOut   string
+		wantedToken    string
+	}{
+		"provided": {
+			arguments: []string{"-token", "abc"},
+
+			wantedExitCode: ExitCodeSuccess,
+			wantedToken:    "abc",
+		},
+		"missing": {
+			wantedExitCode: ExitCodeUsageError,
+			wantedErrOut:   "Error: flag '-token' is required\n\nUsage: test testing\n\nRun 'test --help' for usage.\n",
+		},
+		"from environment": {
+			env: "from-env",
+
+			wantedExitCode: ExitCodeSuccess,
+			wantedToken:    "from-env",
+		},
+		"help requested": {
+			arguments: []string{"--help"},
+
+			wantedExitCode: ExitCodeSuccess,
+		},
+	} {
+		t.Run(testName, func(t *testing.T) {
+			if testData.env != "" {
+				t.Setenv("LIEUT_TEST_TOKEN", testData.env)
+			}
+
+			var out, errOut bytes.Buffer
+
+			flagSet := flag.NewFlagSet(testAppInfo.Name, flag.ContinueOnError)
+			token := flagSet.String("token", "", "The API token")
+
+			_ = SetFlagEnv(flagSet, "token", "LIEUT_TEST_TOKEN")
+			_ = MarkFlagRequired(flagSet, "token")
+
+			app := NewSingleCommandApp(testAppInfo, testNoOpExecutor, flagSet, &out, &errOut)
+
+			exitCode := app.Run(context.TODO(), testData.arguments)
+
+			if exitCode != testData.wantedExitCode {
+				t.Errorf("app.Run gave %v, wanted %v", exitCode, testData.wantedExitCode)
+			}
+
+			if testData.wantedErrOut != "" && errOut.String() != testData.wantedErrOut {
+				t.Errorf("app.Run gave errOut %q, wanted %q", errOut.String(), testData.wantedErrOut)
+			}
+
+			if *token != testData.wantedToken {
+				t.Errorf("app.Run gave token %q, wanted %q", *token, testData.wantedToken)
+			}
+		})
+	}
+}
+
+func TestMultiCommandApp_PrintHelp_Groups(t *testing.T) {
+	wantFormat := `Usage: test testing
+
+A test
+
+Core commands:
+
+	deploy       	Deploy the app
+	status       	Show the status
+
+Admin commands:
+
+	user-add     	Add a user
+	user-remove  	Remove a user
+
+Debug commands:
+
+	dump         	Dump the state
+
+Other:
+
+	completion   	Generate completions
+	version-check	Check for updates
+
+Options:
+
+	-version	Display the application version
+	-help   	Display the help message
+
+test vTest (%s/%s)
+`
+	want := fmt.Sprintf(wantFormat, runtime.GOOS, runtime.GOARCH)
+
+	var buf bytes.Buffer
+
+	app := NewMultiCommandApp(testAppInfo, nil, &buf, &buf)
+
+	for _, info := range []CommandInfo{
+		{Name: "completion", Summary: "Generate completions"},
+		{Name: "user-add", Summary: "Add a user", Group: "Admin commands"},
+		{Name: "deploy", Summary: "Deploy the app", Group: "Core commands"},
+		{Name: "dump", Summary: "Dump the state", Group: "Debug commands"},
+		{Name: "user-remove", Summary: "Remove a user", Group: "Admin commands"},
+		{Name: "status", Summary: "Show the status", Group: "Core commands"},
+		{Name: "version-check", Summary: "Check for updates"},
+		{Name: "secret", Summary: "A hidden command", Group: "Secret commands", Hidden: true},
+	} {
+		_ = app.SetCommand(info, testNoOpExecutor, nil)
+	}
+
+	app.SetGroupOrder("Core commands", "Unused commands", "Admin commands")
+
+	app.PrintHelp("")
+
+	got := buf.String()
+
+	if got != want {
+		t.Errorf("app.PrintHelp gave %q, want %q", got, want)
+	}
+}
+
 func TestMultiCommandApp_CommandOrderIsConsistent(t *testing.T) {
 	seed := time.Now().UnixNano()
 	r := rand.New(rand.NewSource(seed))
@@ -1310,3 +1690,545 @@ Run 'test --help' for usage.
 		})
 	}
 }
+
+func TestMultiCommandApp_Run_DefaultCommand(t *testing.T) {
+	rootHelpOut := fmt.Sprintf(`Usage: test testing
+
+A test
+
+Commands:
+
+	status	Show the status
+	deploy	Deploy the app
+
+Options:
+
+	-verbose	Enable verbose output
+	-version	Display the application version
+	-help   	Display the help message
+
+test vTest (%s/%s)
+`, runtime.GOOS, runtime.GOARCH)
+
+	for testName, testData := range map[string]struct {
+		args []string
+
+		wantedExitCode  int
+		wantedCommand   string
+		wantedArguments []string
+		wantedVerbose   bool
+		wantedOut       string
+		wantedErrOut    string
+	}{
+		"no arguments": {
+			args: []string{},
+
+			wantedExitCode: ExitCodeSuccess,
+			wantedCommand:  "status",
+		},
+		"only flags": {
+			args: []string{"-verbose", "-all"},
+
+			wantedExitCode: ExitCodeSuccess,
+			wantedCommand:  "status",
+			wantedVerbose:  true,
+		},
+		"flag value named like a root flag": {
+			args: []string{"-format", "version"},
+
+			wantedExitCode: ExitCodeSuccess,
+			wantedCommand:  "status",
+		},
+		"flag value like a root flag": {
+			args: []string{"-format", "-h"},
+
+			wantedExitCode: ExitCodeSuccess,
+			wantedCommand:  "status",
+		},
+		"flags and terminated arguments": {
+			args: []string{"-all", "--", "arg"},
+
+			wantedExitCode:  ExitCodeSuccess,
+			wantedCommand:   "status",
+			wantedArguments: []string{"arg"},
+		},
+		"explicit command": {
+			args: []string{"deploy", "arg"},
+
+			wantedExitCode:  ExitCodeSuccess,
+			wantedCommand:   "deploy",
+			wantedArguments: []string{"arg"},
+		},
+		"unknown command": {
+			args: []string{"unknown"},
+
+			wantedExitCode: ExitCodeError,
+			wantedErrOut:   "Error: unknown command 'unknown'\n\nUsage: test testing\n\nRun 'test --help' for usage.\n",
+		},
+		"root help": {
+			args: []string{"-verbose", "--help"},
+
+			wantedExitCode: ExitCodeSuccess,
+			wantedVerbose:  true,
+			wantedErrOut:   rootHelpOut,
+		},
+		"root version": {
+			args: []string{"--version"},
+
+			wantedExitCode: ExitCodeSuccess,
+			wantedOut:      fmt.Sprintf("test vTest (%s/%s)\n", runtime.GOOS, runtime.GOARCH),
+		},
+	} {
+		t.Run(testName, func(t *testing.T) {
+			var out, errOut bytes.Buffer
+
+			var verbose bool
+			flagSet := flag.NewFlagSet(testAppInfo.Name, flag.ContinueOnError)
+			flagSet.BoolVar(&verbose, "verbose", false, "Enable verbose output")
+
+			app := NewMultiCommandApp(testAppInfo, flagSet, &out, &errOut)
+
+			var ranCommand string
+			var ranArguments []string
+			executor := func(name string) Executor {
+				return func(ctx context.Context, arguments []string) error {
+					ranCommand = name
+					ranArguments = arguments
+					return nil
+				}
+			}
+
+			statusFlagSet := flag.NewFlagSet("status", flag.ContinueOnError)
+			statusFlagSet.Bool("all", false, "Show all")
+			statusFlagSet.String("format", "text", "The output format")
+
+			statusInfo := CommandInfo{Name: "status", Summary: "Show the status"}
+
+			_ = app.SetCommand(statusInfo, executor("status"), statusFlagSet)
+			_ = app.SetCommand(CommandInfo{Name: "deploy", Summary: "Deploy the app"}, executor("deploy"), nil)
+
+			app.SetDefaultCommand("status")
+
+			exitCode := app.Run(context.TODO(), testData.args)
+
+			if exitCode != testData.wantedExitCode {
+				t.Errorf("app.Run gave %v, wanted %v", exitCode, testData.wantedExitCode)
+			}
+
+			if ranCommand != testData.wantedCommand {
+				t.Errorf("app.Run ran command %q, wanted %q", ranCommand, testData.wantedCommand)
+			}
+
+			if fmt.Sprint(ranArguments) != fmt.Sprint(testData.wantedArguments) {
+				t.Errorf("app.Run gave arguments %q, wanted %q", ranArguments, testData.wantedArguments)
+			}
+
+			if verbose != testData.wantedVerbose {
+				t.Errorf("app.Run gave verbose %v, wanted %v", verbose, testData.wantedVerbose)
+			}
+
+			if out.String() != testData.wantedOut {
+				t.Errorf("app.Run gave out %q, wanted %q", out.String(), testData.wantedOut)
+			}
+
+			if errOut.String() != testData.wantedErrOut {
+				t.Errorf("app.Run gave errOut %q, wanted %q", errOut.String(), testData.wantedErrOut)
+			}
+		})
+	}
+}
+
+func TestMultiCommandApp_Run_Interspersed(t *testing.T) {
+	for testName, testData := range map[string]struct {
+		interspersed bool
+
+		wantedForce     bool
+		wantedArguments []string
+	}{
+		"default": {
+			interspersed: false,
+
+			wantedForce:     false,
+			wantedArguments: []string{"prod", "--force", "now"},
+		},
+		"interspersed": {
+			interspersed: true,
+
+			wantedForce:     true,
+			wantedArguments: []string{"prod", "now"},
+		},
+	} {
+		t.Run(testName, func(t *testing.T) {
+			app := NewMultiCommandApp(testAppInfo, nil, io.Discard, io.Discard)
+			app.SetInterspersed(testData.interspersed)
+
+			var force bool
+			commandFlagSet := flag.NewFlagSet("deploy", flag.ContinueOnError)
+			commandFlagSet.BoolVar(&force, "force", false, "Force the deploy")
+
+			var capturedArgs []string
+			executor := func(ctx context.Context, arguments []string) error {
+				capturedArgs = arguments
+				return nil
+			}
+
+			_ = app.SetCommand(CommandInfo{Name: "deploy"}, executor, commandFlagSet)
+
+			exitCode := app.Run(context.TODO(), []string{"deploy", "prod", "--force", "now"})
+
+			if exitCode != ExitCodeSuccess {
+				t.Errorf("app.Run gave %v, wanted %v", exitCode, ExitCodeSuccess)
+			}
+
+			if force != testData.wantedForce {
+				t.Errorf("app.Run gave force %v, wanted %v", force, testData.wantedForce)
+			}
+
+			if fmt.Sprint(capturedArgs) != fmt.Sprint(testData.wantedArguments) {
+				t.Errorf("app.Run executor gave args %q, wanted %q", capturedArgs, testData.wantedArguments)
+			}
+		})
+	}
+}
+
+func TestSingleCommandApp_Run_GNUStyleFlags(t *testing.T) {
+	var verbosity int
+	var output string
+
+	flagSet := flag.NewFlagSet(testAppInfo.Name, flag.ContinueOnError)
+	flagSet.Var(CountValue(&verbosity), "verbose", "Increase verbosity")
+	flagSet.StringVar(&output, "output", "", "The output file")
+
+	_ = SetFlagShorthand(flagSet, "verbose", "v")
+	_ = SetFlagShorthand(flagSet, "output", "o")
+
+	var capturedArgs []string
+	executor := func(ctx context.Context, arguments []string) error {
+		capturedArgs = arguments
+		return nil
+	}
+
+	app := NewSingleCommandApp(testAppInfo, executor, flagSet, io.Discard, io.Discard)
+	app.SetGNUStyleFlags(true)
+
+	exitCode := app.Run(context.TODO(), []string{"-vvofile", "--verbose", "arg"})
+
+	if exitCode != ExitCodeSuccess {
+		t.Errorf("app.Run gave %v, wanted %v", exitCode, ExitCodeSuccess)
+	}
+
+	if verbosity != 3 {
+		t.Errorf("app.Run gave verbosity %d, wanted %d", verbosity, 3)
+	}
+
+	if output != "file" {
+		t.Errorf("app.Run gave output %q, wanted %q", output, "file")
+	}
+
+	if len(capturedArgs) != 1 || capturedArgs[0] != "arg" {
+		t.Errorf("app.Run executor gave args %q, wanted %q", capturedArgs, []string{"arg"})
+	}
+}
+
+func TestMultiCommandApp_Run_ResponseFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "args.txt")
+	if err := os.WriteFile(path, []byte("deploy -force 'first file' second"), 0o600); err != nil {
+		t.Fatalf("os.WriteFile returned error: %v", err)
+	}
+
+	var force bool
+	var capturedArgs []string
+
+	commandFlags := flag.NewFlagSet("deploy", flag.ContinueOnError)
+	commandFlags.BoolVar(&force, "force", false, "Force it")
+
+	executor := func(ctx context.Context, arguments []string) error {
+		capturedArgs = arguments
+		return nil
+	}
+
+	var errOut bytes.Buffer
+
+	app := NewMultiCommandApp(testAppInfo, nil, io.Discard, &errOut)
+	app.SetResponseFiles(true)
+	_ = app.SetCommand(CommandInfo{Name: "deploy"}, executor, commandFlags)
+
+	if exitCode := app.Run(context.TODO(), []string{"@" + path, "@@third"}); exitCode != ExitCodeSuccess {
+		t.Errorf("app.Run gave %v, wanted %v", exitCode, ExitCodeSuccess)
+	}
+
+	if want := []string{"first file", "second", "@third"}; !force || !reflect.DeepEqual(capturedArgs, want) {
+		t.Errorf("app.Run gave force %v and args %q, wanted %v and %q", force, capturedArgs, true, want)
+	}
+
+	if exitCode := app.Run(context.TODO(), []string{"@" + path + ".missing"}); exitCode != ExitCodeUsageError {
+		t.Errorf("app.Run gave %v, wanted %v", exitCode, ExitCodeUsageError)
+	}
+
+	if want := "Error: unable to read response file: "; !strings.HasPrefix(errOut.String(), want) {
+		t.Errorf("app.Run gave errOut %q, wanted prefix %q", errOut.String(), want)
+	}
+}
+
+func TestSingleCommandApp_Run_Repeated(t *testing.T) {
+	executed := 0
+	executor := func(ctx context.Context, arguments []string) error {
+		executed++
+		return nil
+	}
+
+	app := NewSingleCommandApp(testAppInfo, executor, nil, io.Discard, io.Discard)
+
+	for _, run := range []struct {
+		arguments []string
+
+		wantExecuted int
+	}{
+		{arguments: []string{"-help"}, wantExecuted: 0},
+		{arguments: []string{"arg"}, wantExecuted: 1},
+		{arguments: []string{"-version"}, wantExecuted: 1},
+		{arguments: []string{"arg"}, wantExecuted: 2},
+	} {
+		if exitCode := app.Run(context.TODO(), run.arguments); exitCode != ExitCodeSuccess {
+			t.Errorf("app.Run(%q) gave %v, wanted %v", run.arguments, exitCode, ExitCodeSuccess)
+		}
+
+		if executed != run.wantExecuted {
+			t.Errorf("app.Run(%q) executed %d times, wanted %d", run.arguments, executed, run.wantExecuted)
+		}
+	}
+}
+
+func TestMultiCommandApp_Run_Repeated(t *testing.T) {
+	executed := 0
+	executor := func(ctx context.Context, arguments []string) error {
+		executed++
+		return nil
+	}
+
+	app := NewMultiCommandApp(testAppInfo, nil, io.Discard, io.Discard)
+	_ = app.SetCommand(CommandInfo{Name: "deploy"}, executor, nil)
+
+	for _, run := range []struct {
+		arguments []string
+
+		wantExecuted int
+	}{
+		{arguments: []string{"deploy", "-help"}, wantExecuted: 0},
+		{arguments: []string{"deploy"}, wantExecuted: 1},
+		{arguments: []string{"-version"}, wantExecuted: 1},
+		{arguments: []string{"deploy"}, wantExecuted: 2},
+	} {
+		if exitCode := app.Run(context.TODO(), run.arguments); exitCode != ExitCodeSuccess {
+			t.Errorf("app.Run(%q) gave %v, wanted %v", run.arguments, exitCode, ExitCodeSuccess)
+		}
+
+		if executed != run.wantExecuted {
+			t.Errorf("app.Run(%q) executed %d times, wanted %d", run.arguments, executed, run.wantExecuted)
+		}
+	}
+}
+
+// testListValue is a custom flag value that appends each value that it's set
+// to, without any knowledge of runs.
+type testListValue []string
+
+func (v *testListValue) String() string {
+	return strings.Join(*v, ",")
+}
+
+func (v *testListValue) Set(s string) error {
+	*v = append(*v, s)
+	return nil
+}
+
+func TestMultiCommandApp_Run_KeepsFlagValues(t *testing.T) {
+	flags := flag.NewFlagSet("deploy", flag.ContinueOnError)
+	timezone := flags.String("timezone", "UTC", "The timezone")
+	tags := []string{"default"}
+	flags.Var(StringSliceValue(&tags), "tag", "A tag")
+	var items testListValue
+	flags.Var(&items, "item", "An item")
+
+	// Preset a value after defining the flag, such as from a config file
+	*timezone = "from-config"
+
+	type values struct {
+		timezone    string
+		tags, items []string
+	}
+
+	var got []values
+	executor := func(ctx context.Context, arguments []string) error {
+		got = append(got, values{*timezone, tags, items})
+		return nil
+	}
+
+	app := NewMultiCommandApp(testAppInfo, nil, io.Discard, io.Discard)
+	_ = app.SetCommand(CommandInfo{Name: "deploy"}, executor, flags)
+
+	for _, arguments := range [][]string{
+		{"deploy"},
+		{"deploy", "-tag=a", "-tag=b", "-item=a", "-item=b"},
+		{"deploy", "-tag=c", "-item=c"},
+	} {
+		if exitCode := app.Run(context.TODO(), arguments); exitCode != ExitCodeSuccess {
+			t.Errorf("app.Run(%q) gave %v, wanted %v", arguments, exitCode, ExitCodeSuccess)
+		}
+	}
+
+	want := []values{
+		{"from-config", []string{"default"}, nil},
+		{"from-config", []string{"a", "b"}, []string{"a", "b"}},
+		{"from-config", []string{"c"}, []string{"a", "b", "c"}},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("app.Run gave values %+v, wanted %+v", got, want)
+	}
+}
+
+// testBuildNameCommand returns a build function for a command with a name flag,
+// which sends the name and arguments it's run with to the given channel.
+func testBuildNameCommand(results chan<- string) func() (Flags, Executor, error) {
+	return func() (Flags, Executor, error) {
+		flags := flag.NewFlagSet("test", flag.ContinueOnError)
+		name := flags.String("name", "none", "A name")
+
+		executor := func(ctx context.Context, arguments []string) error {
+			results <- *name + ":" + strings.Join(arguments, ",")
+			return nil
+		}
+
+		return flags, executor, nil
+	}
+}
+
+func TestNewSingleCommandAppFunc(t *testing.T) {
+	results := make(chan string, 10)
+
+	app, err := NewSingleCommandAppFunc(testAppInfo, testBuildNameCommand(results), io.Discard, io.Discard)
+	if err != nil {
+		t.Fatalf("NewSingleCommandAppFunc returned error: %v", err)
+	}
+
+	runConcurrently(t, app, results, func(i int) []string {
+		return []string{fmt.Sprintf("-name=%d", i), "arg"}
+	})
+
+	if exitCode := app.Run(context.TODO(), []string{"arg"}); exitCode != ExitCodeSuccess {
+		t.Errorf("app.Run gave %v, wanted %v", exitCode, ExitCodeSuccess)
+	}
+
+	if got, want := <-results, "none:arg"; got != want {
+		t.Errorf("app.Run executor gave %q, wanted %q", got, want)
+	}
+
+	wantErr := errors.New("build failed")
+	_, err = NewSingleCommandAppFunc(testAppInfo, func() (Flags, Executor, error) {
+		return nil, nil, wantErr
+	}, io.Discard, io.Discard)
+
+	if !errors.Is(err, wantErr) {
+		t.Errorf("NewSingleCommandAppFunc returned error %v, wanted %v", err, wantErr)
+	}
+}
+
+func TestMultiCommandApp_SetCommandFunc(t *testing.T) {
+	results := make(chan string, 10)
+
+	app := NewMultiCommandApp(testAppInfo, nil, io.Discard, io.Discard)
+
+	if err := app.SetCommandFunc(CommandInfo{Name: "greet"}, testBuildNameCommand(results)); err != nil {
+		t.Fatalf("app.SetCommandFunc returned error: %v", err)
+	}
+
+	runConcurrently(t, app, results, func(i int) []string {
+		return []string{"greet", fmt.Sprintf("-name=%d", i), "arg"}
+	})
+
+	wantErr := errors.New("build failed")
+	err := app.SetCommandFunc(CommandInfo{Name: "broken"}, func() (Flags, Executor, error) {
+		return nil, nil, wantErr
+	})
+
+	if !errors.Is(err, wantErr) {
+		t.Errorf("app.SetCommandFunc returned error %v, wanted %v", err, wantErr)
+	}
+
+	if names := app.CommandNames(); !reflect.DeepEqual(names, []string{"greet"}) {
+		t.Errorf("app.CommandNames gave %q, wanted %q", names, []string{"greet"})
+	}
+}
+
+func TestNewMultiCommandAppFunc(t *testing.T) {
+	results := make(chan string, 10)
+
+	app, err := NewMultiCommandAppFunc(testAppInfo, func() (Flags, error) {
+		flags := flag.NewFlagSet(testAppInfo.Name, flag.ContinueOnError)
+		flags.String("region", "none", "The region")
+
+		return flags, nil
+	}, io.Discard, io.Discard)
+	if err != nil {
+		t.Fatalf("NewMultiCommandAppFunc returned error: %v", err)
+	}
+
+	err = app.SetCommandFunc(CommandInfo{Name: "deploy"}, func() (Flags, Executor, error) {
+		executor := func(ctx context.Context, arguments []string) error {
+			region, _ := FlagStateFromContext(ctx, "region")
+			results <- region.Value + ":" + strings.Join(arguments, ",")
+			return nil
+		}
+
+		return nil, executor, nil
+	})
+	if err != nil {
+		t.Fatalf("app.SetCommandFunc returned error: %v", err)
+	}
+
+	runConcurrently(t, app, results, func(i int) []string {
+		return []string{"deploy", fmt.Sprintf("-region=%d", i), "arg"}
+	})
+
+	wantErr := errors.New("build failed")
+	_, err = NewMultiCommandAppFunc(testAppInfo, func() (Flags, error) {
+		return nil, wantErr
+	}, io.Discard, io.Discard)
+
+	if !errors.Is(err, wantErr) {
+		t.Errorf("NewMultiCommandAppFunc returned error %v, wanted %v", err, wantErr)
+	}
+}
+
+// runConcurrently runs the given app concurrently with the arguments for each
+// run, and checks that each run's executor received its own name flag value.
+func runConcurrently(t *testing.T, app interface {
+	Run(context.Context, []string) int
+}, results <-chan string, arguments func(i int) []string) {
+	t.Helper()
+
+	const runs = 10
+
+	exitCodes := make(chan int, runs)
+	for i := 0; i < runs; i++ {
+		go func(i int) {
+			exitCodes <- app.Run(context.TODO(), arguments(i))
+		}(i)
+	}
+
+	got := make(map[string]bool)
+	for i := 0; i < runs; i++ {
+		if exitCode := <-exitCodes; exitCode != ExitCodeSuccess {
+			t.Errorf("app.Run gave %v, wanted %v", exitCode, ExitCodeSuccess)
+		}
+
+		got[<-results] = true
+	}
+
+	for i := 0; i < runs; i++ {
+		if want := fmt.Sprintf("%d:arg", i); !got[want] {
+			t.Errorf("app.Run executors gave %v, wanted %q", got, want)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright © 2026 Trevor N. Suarez (Rican7)
+
+package lieuttest
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+// UpdateEnv is the name of the environment variable that enables updating
+// golden files, rather than comparing against them, when set to a true value
+// (such as `LIEUTTEST_UPDATE=1 go test ./...`).
+const UpdateEnv = "LIEUTTEST_UPDATE"
+
+// updateFlagName is the name of a test flag that also enables updating golden
+// files, if the test package defines it.
+const updateFlagName = "update"
+
+// shouldUpdate returns whether golden files should be updated, rather than
+// compared against.
+//
+// The flag is looked up lazily (rather than being defined by this package), so
+// that test packages remain free to define their own flag of the same name.
+func shouldUpdate() bool {
+	if update, err := strconv.ParseBool(os.Getenv(UpdateEnv)); err == nil && update {
+		return true
+	}
+
+	f := flag.Lookup(updateFlagName)
+	if f == nil {
+		return false
+	}
+
+	update, err := strconv.ParseBool(f.Value.String())
+
+	return err == nil && update
+}
+
+// AssertGolden asserts that the given string matches the contents of the
+// golden file at the given path.
+//
+// When tests are run with the UpdateEnv environment variable set (or with an
+// `-update` flag, if the test package defines one), the golden file is written
+// with the given string instead (creating any missing directories).
+func AssertGolden(t testing.TB, path string, got string) {
+	t.Helper()
+
+	if shouldUpdate() {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("unable to create golden file directory: %v", err)
+		}
+
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatalf("unable to update golden file: %v", err)
+		}
+
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("unable to read golden file (run with %s=1 to create it): %v", UpdateEnv, err)
+	}
+
+	if got != string(want) {
+		t.Errorf(
+			"output doesn't match golden file %s (run with %s=1 to update it)\ngot:\n%s\nwant:\n%s",
+			path,
+			UpdateEnv,
+			got,
+			want,
+		)
+	}
+}
+
+// AssertStdoutGolden asserts that the app's output matches the contents of the
+// golden file at the given path. See AssertGolden.
+func (r *Result) AssertStdoutGolden(t testing.TB, path string) {
+	t.Helper()
+
+	AssertGolden(t, path, r.Stdout)
+}
+
+// AssertStderrGolden asserts that the app's error output matches the contents
+// of the golden file at the given path. See AssertGolden.
+func (r *Result) AssertStderrGolden(t testing.TB, path string) {
+	t.Helper()
+
+	AssertGolden(t, path, r.Stderr)
+}
@@ -0,0 +1,214 @@
+// Copyright © 2026 Trevor N. Suarez (Rican7)
+
+// Package lieuttest provides utilities for testing lieut apps, by running them
+// in-process and asserting on the results.
+//
+// Apps are run within an overridden environment (see lieut.Environment), so
+// they don't read the process' arguments, environment variables, or standard
+// input, nor handle its signals, which allows tests to run in parallel.
+package lieuttest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Rican7/lieut"
+	"github.com/Rican7/lieut/internal/runreport"
+)
+
+// App is a lieut app that can be run, such as a lieut.SingleCommandApp or a
+// lieut.MultiCommandApp.
+type App interface {
+	Run(ctx context.Context, arguments []string) int
+}
+
+// Options defines options for running an app.
+type Options struct {
+	// Context is the context to run the app with. Defaults to
+	// context.Background().
+	Context context.Context
+
+	// Env defines the environment variables visible to the app. No other
+	// environment variables are visible.
+	Env map[string]string
+
+	// Stdin is the input of the app.
+	Stdin string
+
+	// Dir is the working directory that the app resolves relative paths
+	// against. Defaults to the process' working directory.
+	Dir string
+}
+
+// Result is the result of running an app.
+type Result struct {
+	// Arguments are the arguments that the app was run with.
+	Arguments []string
+
+	// Stdout and Stderr are what the app wrote to its writers.
+	Stdout string
+	Stderr string
+
+	// ExitCode is the exit code returned by the app.
+	ExitCode int
+
+	// Executed is whether a command's Executor was run.
+	Executed bool
+
+	// Err is the error returned by the executed command's Executor, if any.
+	Err error
+
+	// Command is the info of the resolved command, for MultiCommandApps. It's
+	// set once the command is resolved, even if the run stops before the
+	// command is executed (such as for a usage error).
+	Command lieut.CommandInfo
+
+	// CommandPath is the full path of the resolved command, such as
+	// "app deploy", with the same considerations as Command.
+	CommandPath string
+}
+
+// Run runs the given app with the given arguments, and returns the result.
+func Run(app App, arguments ...string) *Result {
+	return RunWithOptions(app, Options{}, arguments...)
+}
+
+// RunWithOptions runs the given app with the given options and arguments, and
+// returns the result.
+func RunWithOptions(app App, options Options, arguments ...string) *Result {
+	ctx := options.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	var stdout, stderr strings.Builder
+
+	result := &Result{Arguments: arguments}
+
+	ctx = lieut.WithEnvironment(ctx, lieut.Environment{
+		Stdout: &stdout,
+		Stderr: &stderr,
+		Stdin:  strings.NewReader(options.Stdin),
+		Dir:    options.Dir,
+		LookupEnv: func(key string) (string, bool) {
+			value, ok := options.Env[key]
+
+			return value, ok
+		},
+		Environ: func() []string {
+			environ := make([]string, 0, len(options.Env))
+			for key, value := range options.Env {
+				environ = append(environ, key+"="+value)
+			}
+
+			return environ
+		},
+	})
+
+	ctx = runreport.WithReporter(ctx, &runreport.Reporter[lieut.CommandInfo]{
+		Resolved: func(command lieut.CommandInfo, commandPath string) {
+			result.Command = command
+			result.CommandPath = commandPath
+		},
+		Executed: func(err error) {
+			result.Executed = true
+			result.Err = err
+		},
+	})
+
+	result.ExitCode = app.Run(ctx, arguments)
+	result.Stdout = stdout.String()
+	result.Stderr = stderr.String()
+
+	return result
+}
+
+// AssertExitCode asserts that the app exited with the given code.
+func (r *Result) AssertExitCode(t testing.TB, want int) {
+	t.Helper()
+
+	if r.ExitCode != want {
+		t.Errorf("%s: exit code %d, want %d\nstderr:\n%s", r, r.ExitCode, want, r.Stderr)
+	}
+}
+
+// AssertSuccess asserts that the app exited successfully.
+func (r *Result) AssertSuccess(t testing.TB) {
+	t.Helper()
+
+	r.AssertExitCode(t, 0)
+}
+
+// AssertStdout asserts that the app's output is exactly the given string.
+func (r *Result) AssertStdout(t testing.TB, want string) {
+	t.Helper()
+
+	if r.Stdout != want {
+		t.Errorf("%s: stdout %q, want %q", r, r.Stdout, want)
+	}
+}
+
+// AssertStderr asserts that the app's error output is exactly the given
+// string.
+func (r *Result) AssertStderr(t testing.TB, want string) {
+	t.Helper()
+
+	if r.Stderr != want {
+		t.Errorf("%s: stderr %q, want %q", r, r.Stderr, want)
+	}
+}
+
+// AssertStdoutContains asserts that the app's output contains the given
+// string.
+func (r *Result) AssertStdoutContains(t testing.TB, want string) {
+	t.Helper()
+
+	if !strings.Contains(r.Stdout, want) {
+		t.Errorf("%s: stdout doesn't contain %q\nstdout:\n%s", r, want, r.Stdout)
+	}
+}
+
+// AssertStderrContains asserts that the app's error output contains the given
+// string.
+func (r *Result) AssertStderrContains(t testing.TB, want string) {
+	t.Helper()
+
+	if !strings.Contains(r.Stderr, want) {
+		t.Errorf("%s: stderr doesn't contain %q\nstderr:\n%s", r, want, r.Stderr)
+	}
+}
+
+// AssertCommand asserts that the command with the given full path (such as
+// "app deploy") was executed.
+func (r *Result) AssertCommand(t testing.TB, want string) {
+	t.Helper()
+
+	switch {
+	case !r.Executed:
+		t.Errorf("%s: no command executed, want %q", r, want)
+	case r.CommandPath != want:
+		t.Errorf("%s: command %q executed, want %q", r, r.CommandPath, want)
+	}
+}
+
+// AssertError asserts that the executed command's Executor returned an error
+// whose message contains the given string, or no error if the string is empty.
+func (r *Result) AssertError(t testing.TB, want string) {
+	t.Helper()
+
+	switch {
+	case want == "" && r.Err != nil:
+		t.Errorf("%s: unexpected error: %v", r, r.Err)
+	case want != "" && r.Err == nil:
+		t.Errorf("%s: no error, want error containing %q", r, want)
+	case want != "" && !strings.Contains(r.Err.Error(), want):
+		t.Errorf("%s: error %q, want error containing %q", r, r.Err, want)
+	}
+}
+
+// String returns a description of the run, for use in messages.
+func (r *Result) String() string {
+	return fmt.Sprintf("run with arguments %q", r.Arguments)
+}
@@ -0,0 +1,193 @@
+package lieuttest
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Rican7/lieut"
+)
+
+func newTestApp(t *testing.T) *lieut.MultiCommandApp {
+	t.Helper()
+
+	// Writers that would fail the test if used directly
+	app := lieut.NewMultiCommandApp(lieut.AppInfo{Name: "test"}, nil, nil, nil)
+
+	greetFlags := flag.NewFlagSet("greet", flag.ContinueOnError)
+	name := greetFlags.String("name", "world", "the name to greet")
+
+	if err := lieut.SetFlagEnv(greetFlags, "name", "GREET_NAME"); err != nil {
+		t.Fatal(err)
+	}
+
+	greet := func(ctx context.Context, arguments []string) error {
+		_, err := fmt.Fprintf(lieut.Stdout(ctx), "Hello, %s!\n", *name)
+
+		return err
+	}
+
+	echo := func(ctx context.Context, arguments []string) error {
+		_, err := io.Copy(lieut.Stdout(ctx), lieut.Stdin(ctx))
+
+		return err
+	}
+
+	fail := func(ctx context.Context, arguments []string) error {
+		return errors.New("failed on purpose")
+	}
+
+	commands := []struct {
+		info  lieut.CommandInfo
+		exec  lieut.Executor
+		flags lieut.Flags
+	}{
+		{lieut.CommandInfo{Name: "greet"}, greet, greetFlags},
+		{lieut.CommandInfo{Name: "echo"}, echo, nil},
+		{lieut.CommandInfo{Name: "fail"}, fail, nil},
+	}
+
+	for _, command := range commands {
+		if err := app.SetCommand(command.info, command.exec, command.flags); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return app
+}
+
+func TestRunWithOptions(t *testing.T) {
+	for testName, testData := range map[string]struct {
+		options   Options
+		arguments []string
+
+		wantExitCode int
+		wantStdout   string
+		wantCommand  string
+		wantErr      string
+	}{
+		"command": {
+			arguments: []string{"greet", "--name", "test"},
+
+			wantStdout:  "Hello, test!\n",
+			wantCommand: "test greet",
+		},
+		"environment variables": {
+			options:   Options{Env: map[string]string{"GREET_NAME": "env"}},
+			arguments: []string{"greet"},
+
+			wantStdout:  "Hello, env!\n",
+			wantCommand: "test greet",
+		},
+		"stdin": {
+			options:   Options{Stdin: "some input\n"},
+			arguments: []string{"echo"},
+
+			wantStdout:  "some input\n",
+			wantCommand: "test echo",
+		},
+		"executor error": {
+			arguments: []string{"fail"},
+
+			wantExitCode: 1,
+			wantCommand:  "test fail",
+			wantErr:      "failed on purpose",
+		},
+	} {
+		testData := testData
+
+		t.Run(testName, func(t *testing.T) {
+			t.Parallel()
+
+			result := RunWithOptions(newTestApp(t), testData.options, testData.arguments...)
+
+			result.AssertExitCode(t, testData.wantExitCode)
+			result.AssertStdout(t, testData.wantStdout)
+			result.AssertCommand(t, testData.wantCommand)
+			result.AssertError(t, testData.wantErr)
+		})
+	}
+}
+
+func TestRun_IsolatesProcess(t *testing.T) {
+	t.Setenv("GREET_NAME", "process")
+
+	originalArgs := os.Args
+	defer func() { os.Args = originalArgs }()
+	os.Args = []string{"test", "greet"}
+
+	result := Run(newTestApp(t))
+
+	result.AssertExitCode(t, 2)
+	result.AssertStderrContains(t, "Usage: test <command>")
+
+	if result.Executed {
+		t.Errorf("executed command %q from the process' arguments", result.CommandPath)
+	}
+
+	result = Run(newTestApp(t), "greet")
+
+	result.AssertStdout(t, "Hello, world!\n")
+}
+
+func TestRun_UsageError(t *testing.T) {
+	result := Run(newTestApp(t), "greet", "--unknown")
+
+	result.AssertExitCode(t, 2)
+	result.AssertStderrContains(t, "flag provided but not defined: -unknown")
+
+	if result.Executed {
+		t.Errorf("executed command %q despite a usage error", result.CommandPath)
+	}
+
+	if result.Command.Name != "greet" || result.CommandPath != "test greet" {
+		t.Errorf("resolved command %q (%+v), want %q", result.CommandPath, result.Command, "test greet")
+	}
+}
+
+func TestAssertGolden(t *testing.T) {
+	result := Run(newTestApp(t), "greet", "--unknown")
+
+	result.AssertStderrGolden(t, filepath.Join("testdata", "usage_error.golden"))
+}
+
+func TestAssertGolden_Update(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "output.golden")
+
+	t.Run("update", func(t *testing.T) {
+		t.Setenv(UpdateEnv, "1")
+
+		AssertGolden(t, path, "some output\n")
+	})
+
+	t.Run("compare", func(t *testing.T) {
+		t.Setenv(UpdateEnv, "")
+
+		AssertGolden(t, path, "some output\n")
+	})
+}
+
+func TestAssertGolden_UpdateFlag(t *testing.T) {
+	// Define the flag like a test package would, as this package doesn't
+	if flag.Lookup(updateFlagName) == nil {
+		flag.Bool(updateFlagName, false, "update golden files")
+	}
+
+	update := flag.Lookup(updateFlagName).Value
+	defer func() { _ = update.Set("false") }()
+
+	t.Setenv(UpdateEnv, "")
+
+	path := filepath.Join(t.TempDir(), "output.golden")
+
+	_ = update.Set("true")
+	AssertGolden(t, path, "some output\n")
+
+	_ = update.Set("false")
+	AssertGolden(t, path, "some output\n")
+}
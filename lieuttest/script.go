@@ -0,0 +1,419 @@
+// Copyright © 2026 Trevor N. Suarez (Rican7)
+
+package lieuttest
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// RunScripts runs each script (`*.txtar` file) in the given directory as a
+// subtest, running the given apps in-process.
+//
+// A script is a txtar archive: its comment section holds the commands to run,
+// one per line, and its files are written into a temporary working directory
+// before the commands are run. Blank lines and lines starting with `#` are
+// ignored. Arguments are separated by spaces, single quotes preserve their
+// contents literally, and environment variables (like `$WORK`, the path of the
+// working directory) are expanded.
+//
+// The supported commands are:
+//
+//	exec <app> [arguments ...]  Run the named app, which must exit with 0.
+//	stdout <pattern>            The last run's output must match the regexp.
+//	stderr <pattern>            The last run's error output must match the regexp.
+//	exit <code>                 The last run must have exited with the code.
+//	cmp <stdout|stderr> <file>  The last run's output must equal the file.
+//	env <key>=<value>           Set an environment variable for later runs.
+//	stdin <file>                Use the file as the input of the next run.
+//
+// Prefixing exec, stdout, or stderr with `!` negates it, so that `! exec`
+// expects a non-zero exit code, and `! stdout .` expects no output.
+//
+// Relative file paths, both in commands and in the apps' arguments (such as
+// response files), are resolved against the script's working directory, without
+// changing the process' working directory.
+func RunScripts(t *testing.T, dir string, apps map[string]func() App) {
+	t.Helper()
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.txtar"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(paths) == 0 {
+		t.Fatalf("no scripts found in %s", dir)
+	}
+
+	for _, path := range paths {
+		path := path
+
+		t.Run(strings.TrimSuffix(filepath.Base(path), ".txtar"), func(t *testing.T) {
+			runScript(t, path, apps)
+		})
+	}
+}
+
+// scriptState is the state of a running script.
+type scriptState struct {
+	apps    map[string]func() App
+	workDir string
+	env     map[string]string
+	stdin   string
+	last    *Result
+}
+
+func runScript(t *testing.T, path string, apps map[string]func() App) {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	script, files, err := parseArchive(string(data))
+	if err != nil {
+		t.Fatalf("%s: %v", path, err)
+	}
+
+	state := &scriptState{
+		apps:    apps,
+		workDir: t.TempDir(),
+	}
+	state.env = map[string]string{"WORK": state.workDir}
+
+	for _, file := range files {
+		filePath := filepath.Join(state.workDir, filepath.FromSlash(file.name))
+
+		if err := os.MkdirAll(filepath.Dir(filePath), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(filePath, []byte(file.data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i, line := range strings.Split(script, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if err := state.run(line); err != nil {
+			if state.last != nil {
+				t.Logf("last run: %s\nstdout:\n%s\nstderr:\n%s", state.last, state.last.Stdout, state.last.Stderr)
+			}
+
+			t.Fatalf("%s:%d: %s: %v", path, i+1, line, err)
+		}
+	}
+}
+
+// run runs a single command line of a script.
+func (s *scriptState) run(line string) error {
+	words, err := s.splitWords(line)
+	if err != nil {
+		return err
+	}
+
+	negated := words[0] == "!"
+	if negated {
+		words = words[1:]
+
+		if len(words) == 0 {
+			return errors.New("missing command after '!'")
+		}
+	}
+
+	command, arguments := words[0], words[1:]
+
+	switch command {
+	case "exec":
+		return s.exec(negated, arguments)
+	case "stdout", "stderr":
+		return s.match(negated, command, arguments)
+	}
+
+	if negated {
+		return fmt.Errorf("command '%s' can't be negated", command)
+	}
+
+	switch command {
+	case "exit":
+		return s.exit(arguments)
+	case "cmp":
+		return s.cmp(arguments)
+	case "env":
+		return s.setEnv(arguments)
+	case "stdin":
+		return s.setStdin(arguments)
+	default:
+		return fmt.Errorf("unknown command '%s'", command)
+	}
+}
+
+func (s *scriptState) exec(negated bool, arguments []string) error {
+	if len(arguments) == 0 {
+		return errors.New("usage: exec <app> [arguments ...]")
+	}
+
+	newApp, ok := s.apps[arguments[0]]
+	if !ok {
+		return fmt.Errorf("unknown app '%s'", arguments[0])
+	}
+
+	env := make(map[string]string, len(s.env))
+	for key, value := range s.env {
+		env[key] = value
+	}
+
+	options := Options{Env: env, Stdin: s.stdin, Dir: s.workDir}
+
+	s.last = RunWithOptions(newApp(), options, arguments[1:]...)
+	s.stdin = ""
+
+	switch {
+	case negated && s.last.ExitCode == 0:
+		return errors.New("unexpected success")
+	case !negated && s.last.ExitCode != 0:
+		return fmt.Errorf("unexpected exit code %d", s.last.ExitCode)
+	}
+
+	return nil
+}
+
+func (s *scriptState) match(negated bool, stream string, arguments []string) error {
+	if len(arguments) != 1 {
+		return fmt.Errorf("usage: %s <pattern>", stream)
+	}
+
+	output, err := s.output(stream)
+	if err != nil {
+		return err
+	}
+
+	pattern, err := regexp.Compile("(?m)" + arguments[0])
+	if err != nil {
+		return err
+	}
+
+	switch matched := pattern.MatchString(output); {
+	case negated && matched:
+		return fmt.Errorf("unexpected match for %q in %s", arguments[0], stream)
+	case !negated && !matched:
+		return fmt.Errorf("no match for %q in %s", arguments[0], stream)
+	}
+
+	return nil
+}
+
+func (s *scriptState) exit(arguments []string) error {
+	if len(arguments) != 1 {
+		return errors.New("usage: exit <code>")
+	}
+
+	want, err := strconv.Atoi(arguments[0])
+	if err != nil {
+		return fmt.Errorf("invalid exit code: %w", err)
+	}
+
+	if s.last == nil {
+		return errors.New("no app has been run")
+	}
+
+	if s.last.ExitCode != want {
+		return fmt.Errorf("exit code %d, want %d", s.last.ExitCode, want)
+	}
+
+	return nil
+}
+
+func (s *scriptState) cmp(arguments []string) error {
+	if len(arguments) != 2 {
+		return errors.New("usage: cmp <stdout|stderr> <file>")
+	}
+
+	output, err := s.output(arguments[0])
+	if err != nil {
+		return err
+	}
+
+	want, err := os.ReadFile(s.path(arguments[1]))
+	if err != nil {
+		return err
+	}
+
+	if output != string(want) {
+		return fmt.Errorf("%s doesn't match %s\ngot:\n%s\nwant:\n%s", arguments[0], arguments[1], output, want)
+	}
+
+	return nil
+}
+
+func (s *scriptState) setEnv(arguments []string) error {
+	for _, argument := range arguments {
+		key, value, ok := strings.Cut(argument, "=")
+		if !ok || key == "" {
+			return errors.New("usage: env <key>=<value> ...")
+		}
+
+		s.env[key] = value
+	}
+
+	return nil
+}
+
+func (s *scriptState) setStdin(arguments []string) error {
+	if len(arguments) != 1 {
+		return errors.New("usage: stdin <file>")
+	}
+
+	data, err := os.ReadFile(s.path(arguments[0]))
+	if err != nil {
+		return err
+	}
+
+	s.stdin = string(data)
+
+	return nil
+}
+
+// path resolves the given file path against the script's working directory.
+func (s *scriptState) path(name string) string {
+	if filepath.IsAbs(name) {
+		return name
+	}
+
+	return filepath.Join(s.workDir, name)
+}
+
+// output returns the named output of the last run.
+func (s *scriptState) output(stream string) (string, error) {
+	if s.last == nil {
+		return "", errors.New("no app has been run")
+	}
+
+	switch stream {
+	case "stdout":
+		return s.last.Stdout, nil
+	case "stderr":
+		return s.last.Stderr, nil
+	default:
+		return "", fmt.Errorf("unknown output '%s'", stream)
+	}
+}
+
+// splitWords splits a line of a script into words, separated by spaces.
+//
+// Single quotes preserve their contents literally (a doubled single quote
+// within them is a literal single quote), and environment variables are
+// expanded outside of them.
+func (s *scriptState) splitWords(line string) ([]string, error) {
+	var words []string
+	var current strings.Builder
+
+	inWord := false
+
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case c == ' ' || c == '\t':
+			if inWord {
+				words = append(words, current.String())
+				current.Reset()
+				inWord = false
+			}
+		case c == '\'':
+			for i++; ; i++ {
+				if i >= len(line) {
+					return nil, errors.New("unterminated single quote")
+				}
+
+				if line[i] == '\'' {
+					if i+1 < len(line) && line[i+1] == '\'' {
+						i++
+					} else {
+						break
+					}
+				}
+
+				current.WriteByte(line[i])
+			}
+
+			inWord = true
+		default:
+			end := strings.IndexAny(line[i:], " \t'")
+			if end < 0 {
+				end = len(line) - i
+			}
+
+			current.WriteString(os.Expand(line[i:i+end], func(key string) string {
+				return s.env[key]
+			}))
+
+			inWord = true
+			i += end - 1
+		}
+	}
+
+	if inWord {
+		words = append(words, current.String())
+	}
+
+	return words, nil
+}
+
+// archiveFile is a file within a txtar archive.
+type archiveFile struct {
+	name string
+	data string
+}
+
+// parseArchive parses a txtar archive, returning its comment and files.
+//
+// Each file begins with a marker line of the form `-- name --`, and runs until
+// the next marker line or the end of the archive.
+func parseArchive(archive string) (comment string, files []archiveFile, err error) {
+	lines := strings.SplitAfter(archive, "\n")
+
+	var current *archiveFile
+	var commentLines strings.Builder
+
+	for _, line := range lines {
+		if name, ok := archiveMarker(line); ok {
+			if name == "" {
+				return "", nil, errors.New("file marker without a name")
+			}
+
+			files = append(files, archiveFile{name: name})
+			current = &files[len(files)-1]
+
+			continue
+		}
+
+		if current == nil {
+			commentLines.WriteString(line)
+		} else {
+			current.data += line
+		}
+	}
+
+	return commentLines.String(), files, nil
+}
+
+// archiveMarker returns the file name of the given line, if it's a file marker.
+func archiveMarker(line string) (string, bool) {
+	line = strings.TrimRight(line, "\r\n")
+
+	if !strings.HasPrefix(line, "-- ") || !strings.HasSuffix(line, " --") || len(line) < len("-- ")+len(" --") {
+		return "", false
+	}
+
+	return strings.TrimSpace(line[len("-- ") : len(line)-len(" --")]), true
+}
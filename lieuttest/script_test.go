@@ -0,0 +1,131 @@
+package lieuttest
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRunScripts(t *testing.T) {
+	t.Parallel()
+
+	RunScripts(t, "testdata/scripts", map[string]func() App{
+		"test": func() App {
+			app := newTestApp(t)
+			app.SetResponseFiles(true)
+
+			return app
+		},
+	})
+}
+
+func TestSplitWords(t *testing.T) {
+	state := &scriptState{env: map[string]string{"WORK": "/tmp/work", "EMPTY": ""}}
+
+	for testName, testData := range map[string]struct {
+		line string
+
+		want    []string
+		wantErr bool
+	}{
+		"spaces": {
+			line: "exec  test\tgreet",
+
+			want: []string{"exec", "test", "greet"},
+		},
+		"single quotes": {
+			line: "stdout 'Hello, world!' '' 'it''s'",
+
+			want: []string{"stdout", "Hello, world!", "", "it's"},
+		},
+		"adjacent quotes": {
+			line: "exec test --name='the world'",
+
+			want: []string{"exec", "test", "--name=the world"},
+		},
+		"environment variables": {
+			line: "cmp stdout $WORK/out.txt ${WORK}x $EMPTY '$WORK'",
+
+			want: []string{"cmp", "stdout", "/tmp/work/out.txt", "/tmp/workx", "", "$WORK"},
+		},
+		"unterminated quote": {
+			line: "stdout 'oops",
+
+			wantErr: true,
+		},
+	} {
+		t.Run(testName, func(t *testing.T) {
+			got, err := state.splitWords(testData.line)
+
+			if (err != nil) != testData.wantErr {
+				t.Fatalf("splitWords returned error %v, want error %t", err, testData.wantErr)
+			}
+
+			if !testData.wantErr && !reflect.DeepEqual(got, testData.want) {
+				t.Errorf("splitWords returned %q, want %q", got, testData.want)
+			}
+		})
+	}
+}
+
+func TestParseArchive(t *testing.T) {
+	for testName, testData := range map[string]struct {
+		archive string
+
+		wantComment string
+		wantFiles   []archiveFile
+		wantErr     bool
+	}{
+		"comment only": {
+			archive: "exec test\n",
+
+			wantComment: "exec test\n",
+		},
+		"files": {
+			archive: "exec test\n-- a.txt --\nA\n-- dir/b.txt --\nB\n\n",
+
+			wantComment: "exec test\n",
+			wantFiles: []archiveFile{
+				{name: "a.txt", data: "A\n"},
+				{name: "dir/b.txt", data: "B\n\n"},
+			},
+		},
+		"empty file": {
+			archive: "-- a.txt --\n-- b.txt --\nB\n",
+
+			wantFiles: []archiveFile{
+				{name: "a.txt"},
+				{name: "b.txt", data: "B\n"},
+			},
+		},
+		"not a marker": {
+			archive: "-- --\n--a.txt--\n",
+
+			wantComment: "-- --\n--a.txt--\n",
+		},
+		"marker without a name": {
+			archive: "--   --\n",
+
+			wantErr: true,
+		},
+	} {
+		t.Run(testName, func(t *testing.T) {
+			comment, files, err := parseArchive(testData.archive)
+
+			if (err != nil) != testData.wantErr {
+				t.Fatalf("parseArchive returned error %v, want error %t", err, testData.wantErr)
+			}
+
+			if testData.wantErr {
+				return
+			}
+
+			if comment != testData.wantComment {
+				t.Errorf("parseArchive returned comment %q, want %q", comment, testData.wantComment)
+			}
+
+			if !reflect.DeepEqual(files, testData.wantFiles) {
+				t.Errorf("parseArchive returned files %+v, want %+v", files, testData.wantFiles)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright © 2026 Trevor N. Suarez (Rican7)
+
+package lieut
+
+import (
+	"context"
+	"errors"
+)
+
+// Mount sets a command for the given SingleCommandApp, so that an existing app
+// can be run as a command of the MultiCommandApp, such as when folding a tool
+// into an umbrella app.
+//
+// The command runs the app's executor, after the app's init function (if any),
+// with the app's flags merged with the global flags, as if it were set via
+// SetCommand. The given info's Name, Summary, and Usage default to those of the
+// app's info, if empty. Only the settings of the MultiCommandApp (such as
+// strict deprecations) apply to the command.
+//
+// Mounting takes over the app's flags, hiding its version flag (as the version
+// is of the MultiCommandApp), and so the app shouldn't be run on its own
+// afterwards.
+//
+// It returns an error for any of the reasons that SetCommand does.
+func (a *MultiCommandApp) Mount(single *SingleCommandApp, info CommandInfo) error {
+	if info.Name == "" {
+		info.Name = single.info.Name
+	}
+
+	if info.Summary == "" {
+		info.Summary = single.info.Summary
+	}
+
+	if info.Usage == "" {
+		info.Usage = single.info.Usage
+	}
+
+	if !a.isUniqueFlagSet(single.flags.Flags) {
+		return errors.New("provided flags are duplicate")
+	}
+
+	// The version flag may not be defined, if the flags don't support it
+	_ = MarkFlagHidden(single.flags.Flags, "version")
+
+	if err := a.mergeGlobalFlags(single.flags); err != nil {
+		return err
+	}
+
+	cmd := command{info: info, Executor: single.mountedExecutor(single.exec), flags: single.flags}
+
+	if single.build != nil {
+		cmd.build = func() (Flags, Executor, error) {
+			flags, exec, err := single.build()
+			if err != nil {
+				return nil, nil, err
+			}
+
+			return flags, single.mountedExecutor(exec), nil
+		}
+	}
+
+	if _, hasCommand := a.commands[info.Name]; !hasCommand {
+		a.commandNames = append(a.commandNames, info.Name)
+	}
+
+	a.commands[info.Name] = cmd
+
+	return nil
+}
+
+// mountedExecutor returns an executor that runs the app's init function before
+// the given executor, for when the app is mounted as a command.
+func (a *SingleCommandApp) mountedExecutor(exec Executor) Executor {
+	return func(ctx context.Context, arguments []string) error {
+		if err := a.initialize(); err != nil {
+			return err
+		}
+
+		return exec(ctx, arguments)
+	}
+}
@@ -0,0 +1,148 @@
+package lieut
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestMultiCommandApp_Mount(t *testing.T) {
+	var errOut bytes.Buffer
+
+	var captured struct {
+		name      string
+		verbose   bool
+		arguments []string
+		initRan   bool
+	}
+
+	toolFlags := flag.NewFlagSet("tool", flag.ContinueOnError)
+	toolFlags.StringVar(&captured.name, "name", "world", "The name")
+
+	toolInfo := AppInfo{Name: "tool", Summary: "A tool", Usage: "[names ...]", Version: "v1"}
+
+	tool := NewSingleCommandApp(toolInfo, func(ctx context.Context, arguments []string) error {
+		captured.arguments = arguments
+
+		if len(arguments) > 0 && arguments[0] == "fail" {
+			return errors.New("tool failed")
+		}
+
+		return nil
+	}, toolFlags, io.Discard, io.Discard)
+
+	tool.OnInit(func() error {
+		captured.initRan = true
+		return nil
+	})
+
+	globalFlags := flag.NewFlagSet("test", flag.ContinueOnError)
+	globalFlags.BoolVar(&captured.verbose, "verbose", false, "Be verbose")
+
+	app := NewMultiCommandApp(testAppInfo, globalFlags, io.Discard, &errOut)
+
+	if err := app.Mount(tool, CommandInfo{Group: "Tools"}); err != nil {
+		t.Fatalf("app.Mount returned error: %v", err)
+	}
+
+	if err := app.Mount(tool, CommandInfo{Name: "again"}); err == nil {
+		t.Error("app.Mount returned no error for duplicate flags")
+	}
+
+	t.Run("runs the app", func(t *testing.T) {
+		exitCode := app.Run(context.TODO(), []string{"tool", "-name", "test", "-verbose", "arg"})
+		if exitCode != ExitCodeSuccess {
+			t.Errorf("app.Run gave %v, wanted %v", exitCode, ExitCodeSuccess)
+		}
+
+		arguments := strings.Join(captured.arguments, ",")
+		if !captured.initRan || captured.name != "test" || !captured.verbose || arguments != "arg" {
+			t.Errorf("app.Run executor captured %+v", captured)
+		}
+	})
+
+	t.Run("handles errors", func(t *testing.T) {
+		errOut.Reset()
+
+		if exitCode := app.Run(context.TODO(), []string{"tool", "fail"}); exitCode != ExitCodeError {
+			t.Errorf("app.Run gave %v, wanted %v", exitCode, ExitCodeError)
+		}
+
+		if got, want := errOut.String(), "Error: tool failed\n"; got != want {
+			t.Errorf("app.Run gave errOut %q, wanted %q", got, want)
+		}
+
+		errOut.Reset()
+
+		if exitCode := app.Run(context.TODO(), []string{"tool", "-bogus"}); exitCode != ExitCodeUsageError {
+			t.Errorf("app.Run gave %v, wanted %v", exitCode, ExitCodeUsageError)
+		}
+
+		want := "Error: flag provided but not defined: -bogus\n\n" +
+			"Usage: test tool [names ...]\n\n" +
+			"Run 'test tool --help' for usage.\n"
+
+		if got := errOut.String(); got != want {
+			t.Errorf("app.Run gave errOut %q, wanted %q", got, want)
+		}
+	})
+
+	t.Run("displays help", func(t *testing.T) {
+		errOut.Reset()
+
+		app.PrintHelp("tool")
+
+		want := "Usage: test tool [names ...]\n\n" +
+			"A tool\n\n" +
+			"Options:\n\n" +
+			"\t-name string\tThe name (default \"world\")\n" +
+			"\t-verbose    \tBe verbose\n" +
+			"\t-help       \tDisplay the help message\n\n"
+
+		if got := errOut.String(); !strings.HasPrefix(got, want) {
+			t.Errorf("app.PrintHelp gave %q, wanted prefix %q", got, want)
+		}
+
+		errOut.Reset()
+
+		app.PrintHelp("")
+
+		if want := "\nTools:\n\n\ttool\tA tool\n"; !strings.Contains(errOut.String(), want) {
+			t.Errorf("app.PrintHelp gave %q, wanted it to contain %q", errOut.String(), want)
+		}
+	})
+}
+
+func TestMultiCommandApp_Mount_Func(t *testing.T) {
+	results := make(chan string, 10)
+
+	tool, err := NewSingleCommandAppFunc(AppInfo{Name: "tool"}, testBuildNameCommand(results), io.Discard, io.Discard)
+	if err != nil {
+		t.Fatalf("NewSingleCommandAppFunc returned error: %v", err)
+	}
+
+	initRuns := make(chan struct{}, 10)
+	tool.OnInit(func() error {
+		initRuns <- struct{}{}
+		return nil
+	})
+
+	app := NewMultiCommandApp(testAppInfo, nil, io.Discard, io.Discard)
+
+	if err := app.Mount(tool, CommandInfo{Name: "greet"}); err != nil {
+		t.Fatalf("app.Mount returned error: %v", err)
+	}
+
+	runConcurrently(t, app, results, func(i int) []string {
+		return []string{"greet", fmt.Sprintf("-name=%d", i), "arg"}
+	})
+
+	if got := len(initRuns); got != 10 {
+		t.Errorf("app.Run ran the init function %d times, wanted %d", got, 10)
+	}
+}
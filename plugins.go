@@ -0,0 +1,318 @@
+// Copyright © 2026 Trevor N. Suarez (Rican7)
+
+package lieut
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// Environment variables provided to plugin commands.
+const (
+	// PluginEnvAppName is the environment variable holding the app's name.
+	PluginEnvAppName = "LIEUT_APP_NAME"
+
+	// PluginEnvAppVersion is the environment variable holding the app's
+	// version.
+	PluginEnvAppVersion = "LIEUT_APP_VERSION"
+
+	// PluginEnvCommandPath is the environment variable holding the full path of
+	// the plugin command, such as "app foo".
+	PluginEnvCommandPath = "LIEUT_COMMAND_PATH"
+
+	// PluginEnvFlagPrefix is the prefix of the environment variables holding
+	// the values of the app's global flags, such as LIEUT_FLAG_DRY_RUN for a
+	// `--dry-run` flag.
+	PluginEnvFlagPrefix = "LIEUT_FLAG_"
+)
+
+// SetPlugins sets whether unknown commands should be run as plugin commands,
+// in the style of git.
+//
+// When enabled, an unknown command `foo` is run as the executable named
+// `<app>-foo`, found in the given plugin directories or in the directories of
+// the PATH environment variable, in that order. The plugin is given the
+// remaining arguments, the app's I/O, and the app's environment variables, and
+// the app exits with the plugin's exit code. The app's name and version, and
+// the values of its global flags (which are parsed from any arguments before
+// the plugin's name, but aren't required), are provided to the plugin via
+// environment variables (see PluginEnvAppName, etc). Discovered plugins are
+// listed in the app's help.
+func (a *MultiCommandApp) SetPlugins(enabled bool, dirs ...string) {
+	a.plugins = enabled
+	a.pluginDirs = append([]string(nil), dirs...)
+}
+
+// pluginPrefix returns the prefix of the executable names of the app's plugins.
+func (a *MultiCommandApp) pluginPrefix() string {
+	return a.info.Name + "-"
+}
+
+// pluginSearchDirs returns the directories to search for plugins, in order.
+func (a *MultiCommandApp) pluginSearchDirs() []string {
+	dirs := append([]string(nil), a.pluginDirs...)
+
+	if path, ok := a.lookupEnv("PATH"); ok {
+		dirs = append(dirs, filepath.SplitList(path)...)
+	}
+
+	return dirs
+}
+
+// findPlugin returns the path of the executable of the named plugin command.
+func (a *MultiCommandApp) findPlugin(name string) (string, bool) {
+	if !a.plugins || name == "" || strings.ContainsAny(name, `/\`) {
+		return "", false
+	}
+
+	for _, dir := range a.pluginSearchDirs() {
+		if dir == "" {
+			continue
+		}
+
+		for _, fileName := range pluginFileNames(a.pluginPrefix() + name) {
+			path := filepath.Join(dir, fileName)
+
+			if isExecutableFile(path) {
+				return path, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// pluginNames returns the names of the discovered plugin commands, excluding
+// any that are shadowed by the app's commands.
+func (a *MultiCommandApp) pluginNames() []string {
+	if !a.plugins {
+		return nil
+	}
+
+	prefix := a.pluginPrefix()
+	isListed := make(map[string]bool)
+
+	var names []string
+	for _, dir := range a.pluginSearchDirs() {
+		if dir == "" {
+			continue
+		}
+
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+
+		for _, entry := range entries {
+			name, isPlugin := strings.CutPrefix(entry.Name(), prefix)
+			if !isPlugin {
+				continue
+			}
+
+			if runtime.GOOS == "windows" {
+				name = strings.TrimSuffix(name, filepath.Ext(name))
+			}
+
+			if _, hasCommand := a.commands[name]; name == "" || hasCommand || isListed[name] {
+				continue
+			}
+
+			if isExecutableFile(filepath.Join(dir, entry.Name())) {
+				isListed[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// runPlugin runs the plugin command named by the first argument after any
+// global flags, and returns its exit code, if there's such a plugin command.
+//
+// The global flags are parsed before the plugin command's name, while the
+// arguments after its name are passed to the plugin command as they are.
+func (a *MultiCommandApp) runPlugin(ctx context.Context, run runMetadata, arguments []string) (int, bool) {
+	if !a.plugins {
+		return 0, false
+	}
+
+	flagCount := leadingFlagCount(a.flags.Flags, arguments)
+	if flagCount >= len(arguments) {
+		return 0, false
+	}
+
+	name := arguments[flagCount]
+	if _, hasCommand := a.commands[name]; hasCommand {
+		return 0, false
+	}
+
+	path, isPlugin := a.findPlugin(name)
+	if !isPlugin {
+		return 0, false
+	}
+
+	run.commandPath = a.fullCommandName("") + " " + name
+	a.reporter.ReportResolved(CommandInfo{}, run.commandPath)
+
+	env, exitCode, ok := a.parsePlugin(run.commandPath, arguments[:flagCount])
+	if !ok {
+		return exitCode, true
+	}
+
+	executor := func(ctx context.Context, arguments []string) error {
+		cmd := exec.CommandContext(ctx, path, arguments...)
+		cmd.Stdin = Stdin(ctx)
+		cmd.Stdout = Stdout(ctx)
+		cmd.Stderr = Stderr(ctx)
+		cmd.Env = env
+		cmd.Dir = a.workDir()
+
+		// Let the plugin handle cancellation (like an interrupt) itself
+		cmd.Cancel = func() error {
+			return cmd.Process.Signal(os.Interrupt)
+		}
+
+		err := cmd.Run()
+
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() >= 0 {
+			// The plugin is responsible for displaying its own errors
+			return ErrWithStatusCode(errors.New(""), exitErr.ExitCode())
+		}
+
+		if err != nil {
+			// Such as when the plugin was killed by a signal
+			return fmt.Errorf("unable to run plugin '%s': %w", name, err)
+		}
+
+		return nil
+	}
+
+	return a.execute(ctx, run, executor, arguments[flagCount+1:]), true
+}
+
+// parsePlugin parses the given global flag arguments of a run of a plugin
+// command, while holding the app's parse lock, and returns the environment
+// variables that provide the app's context to the plugin command with the given
+// full path.
+//
+// Required global flags aren't enforced, as the plugin command may not need
+// them. It returns an exit code and false if the run should stop.
+func (a *MultiCommandApp) parsePlugin(commandPath string, arguments []string) ([]string, int, bool) {
+	a.parseMu.Lock()
+	defer a.parseMu.Unlock()
+
+	if err := a.buildGlobalFlags(); err != nil {
+		return nil, a.handleError(err), false
+	}
+
+	if err := a.parseFlags(a.flags, arguments); err != nil {
+		a.PrintUsageError("", err)
+		return nil, ExitCodeUsageError, false
+	}
+
+	if intercepted := a.intercept(a.flags, ""); intercepted {
+		return nil, ExitCodeSuccess, false
+	}
+
+	if err := a.handleDeprecations(deprecatedFlagNotices(a.flags)); err != nil {
+		a.PrintUsageError("", err)
+		return nil, ExitCodeUsageError, false
+	}
+
+	_, err := setFlagsFromEnv(a.flags, a.lookupEnv)
+	a.useErrorFormatFlag(a.flags)
+
+	if err != nil {
+		a.PrintUsageError("", err)
+		return nil, ExitCodeUsageError, false
+	}
+
+	env := append(a.environ(),
+		PluginEnvAppName+"="+a.info.Name,
+		PluginEnvAppVersion+"="+a.info.Version,
+		PluginEnvCommandPath+"="+commandPath,
+	)
+
+	visitFlags(a.flags.Flags, func(f flagInfo) {
+		if f.name == "help" || f.name == "version" {
+			return
+		}
+
+		key := strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(f.name))
+		env = append(env, PluginEnvFlagPrefix+key+"="+f.value)
+	})
+
+	return env, ExitCodeSuccess, true
+}
+
+// leadingFlagCount returns the number of leading arguments that are flags of
+// the given flags, including the values of flags that take one, and any
+// argument terminator that ends them.
+func leadingFlagCount(flags Flags, arguments []string) int {
+	takesValue := valueFlagNames(flags)
+
+	for i := 0; i < len(arguments); i++ {
+		switch argument := arguments[i]; {
+		case argument == argumentTerminator:
+			return i + 1
+		case !isFlagArgument(argument):
+			return i
+		}
+
+		name, _, hasValue := strings.Cut(strings.TrimLeft(arguments[i], "-"), "=")
+		if !hasValue && takesValue[name] {
+			i++
+		}
+	}
+
+	return len(arguments)
+}
+
+func (a *MultiCommandApp) printPlugins() {
+	names := a.pluginNames()
+	if len(names) == 0 {
+		return
+	}
+
+	fmt.Fprintf(a.errOut, "\nPlugin commands:\n\n")
+
+	for _, name := range names {
+		fmt.Fprintf(a.errOut, "\t%s\n", name)
+	}
+}
+
+// pluginFileNames returns the possible file names of the executable with the
+// given name, for the current platform.
+func pluginFileNames(name string) []string {
+	if runtime.GOOS == "windows" {
+		return []string{name + ".exe", name + ".bat", name + ".cmd"}
+	}
+
+	return []string{name}
+}
+
+// isExecutableFile returns whether the file at the given path is an executable
+// regular file.
+func isExecutableFile(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		return false
+	}
+
+	if runtime.GOOS == "windows" {
+		return true
+	}
+
+	return info.Mode().Perm()&0o111 != 0
+}
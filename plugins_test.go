@@ -0,0 +1,238 @@
+package lieut
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func writeTestPlugin(t *testing.T, dir string, name string, script string) {
+	t.Helper()
+
+	if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+script+"\n"), 0o755); err != nil {
+		t.Fatalf("os.WriteFile returned error: %v", err)
+	}
+}
+
+func TestMultiCommandApp_Run_Plugins(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugin scripts require a POSIX shell")
+	}
+
+	pluginDir := t.TempDir()
+	pathDir := t.TempDir()
+
+	t.Setenv("PATH", pathDir)
+
+	writeTestPlugin(
+		t,
+		pluginDir,
+		"test-greet",
+		`echo "hello $* from $LIEUT_APP_NAME $LIEUT_APP_VERSION ($LIEUT_COMMAND_PATH)"`+
+			` "$LIEUT_FLAG_DRY_RUN $LIEUT_FLAG_TOKEN"`,
+	)
+	writeTestPlugin(t, pluginDir, "test-env", `echo "$TEST_VAR $TEST_PROCESS_VAR $(pwd)"`)
+	writeTestPlugin(t, pluginDir, "test-killed", `kill -9 $$`)
+	writeTestPlugin(t, pathDir, "test-greet", `echo "shadowed"`)
+	writeTestPlugin(t, pathDir, "test-fail", `read input; echo "failing on $input" >&2; exit 3`)
+	writeTestPlugin(t, pathDir, "test-deploy", `echo "shadowed by a command"`)
+	writeTestPlugin(t, pathDir, "other-app", `echo "another app's plugin"`)
+
+	if err := os.WriteFile(filepath.Join(pathDir, "test-data"), nil, 0o644); err != nil {
+		t.Fatalf("os.WriteFile returned error: %v", err)
+	}
+
+	newApp := func(enabled bool, out io.Writer, errOut io.Writer) *MultiCommandApp {
+		globalFlags := flag.NewFlagSet("test", flag.ContinueOnError)
+		globalFlags.Bool("dry-run", false, "Don't do it")
+		globalFlags.String("token", "none", "The token")
+		_ = SetFlagEnv(globalFlags, "dry-run", "TEST_DRY_RUN")
+		_ = MarkFlagRequired(globalFlags, "token")
+
+		app := NewMultiCommandApp(testAppInfo, globalFlags, out, errOut)
+		app.SetPlugins(enabled, pluginDir)
+		_ = app.SetCommand(CommandInfo{Name: "deploy"}, testNoOpExecutor, nil)
+
+		return app
+	}
+
+	t.Run("runs plugins", func(t *testing.T) {
+		t.Setenv("TEST_DRY_RUN", "true")
+
+		var out bytes.Buffer
+
+		app := newApp(true, &out, io.Discard)
+
+		if exitCode := app.Run(context.TODO(), []string{"greet", "a", "b"}); exitCode != ExitCodeSuccess {
+			t.Errorf("app.Run gave %v, wanted %v", exitCode, ExitCodeSuccess)
+		}
+
+		if got, want := out.String(), "hello a b from test vTest (test greet) true none\n"; got != want {
+			t.Errorf("app.Run gave out %q, wanted %q", got, want)
+		}
+	})
+
+	t.Run("parses global flags before the plugin name", func(t *testing.T) {
+		var out bytes.Buffer
+
+		arguments := []string{"-dry-run", "-token", "abc", "greet", "-token", "def"}
+		app := newApp(true, &out, io.Discard)
+
+		if exitCode := app.Run(context.TODO(), arguments); exitCode != ExitCodeSuccess {
+			t.Errorf("app.Run gave %v, wanted %v", exitCode, ExitCodeSuccess)
+		}
+
+		if got, want := out.String(), "hello -token def from test vTest (test greet) true abc\n"; got != want {
+			t.Errorf("app.Run gave out %q, wanted %q", got, want)
+		}
+	})
+
+	t.Run("runs plugins named after global flags with a default command", func(t *testing.T) {
+		var out bytes.Buffer
+		var defaultArguments [][]string
+
+		app := newApp(true, &out, io.Discard)
+		_ = app.SetCommand(CommandInfo{Name: "status"}, func(ctx context.Context, arguments []string) error {
+			defaultArguments = append(defaultArguments, arguments)
+			return nil
+		}, nil)
+		app.SetDefaultCommand("status")
+
+		for _, arguments := range [][]string{
+			{"-token", "abc", "greet", "a"},
+			{"-token", "abc"},
+			{"-token", "abc", "unknown"},
+		} {
+			if exitCode := app.Run(context.TODO(), arguments); exitCode != ExitCodeSuccess {
+				t.Errorf("app.Run(%q) gave %v, wanted %v", arguments, exitCode, ExitCodeSuccess)
+			}
+		}
+
+		if got, want := out.String(), "hello a from test vTest (test greet) false abc\n"; got != want {
+			t.Errorf("app.Run gave out %q, wanted %q", got, want)
+		}
+
+		if want := [][]string{{}, {"unknown"}}; !reflect.DeepEqual(defaultArguments, want) {
+			t.Errorf("app.Run gave default command arguments %q, wanted %q", defaultArguments, want)
+		}
+	})
+
+	t.Run("invalid global flags", func(t *testing.T) {
+		var errOut bytes.Buffer
+
+		arguments := []string{"-unknown", "greet"}
+		app := newApp(true, io.Discard, &errOut)
+
+		if exitCode := app.Run(context.TODO(), arguments); exitCode != ExitCodeUsageError {
+			t.Errorf("app.Run gave %v, wanted %v", exitCode, ExitCodeUsageError)
+		}
+
+		if want := "Error: flag provided but not defined: -unknown"; !strings.Contains(errOut.String(), want) {
+			t.Errorf("app.Run gave errOut %q, wanted it to contain %q", errOut.String(), want)
+		}
+	})
+
+	t.Run("uses the app's environment", func(t *testing.T) {
+		t.Setenv("TEST_PROCESS_VAR", "process")
+
+		var out bytes.Buffer
+
+		workDir := t.TempDir()
+		ctx := WithEnvironment(context.TODO(), Environment{
+			Stdout: &out,
+			LookupEnv: func(key string) (string, bool) {
+				return "", false
+			},
+			Environ: func() []string {
+				return []string{"TEST_VAR=injected"}
+			},
+			Dir: workDir,
+		})
+
+		app := newApp(true, io.Discard, io.Discard)
+
+		if exitCode := app.Run(ctx, []string{"env"}); exitCode != ExitCodeSuccess {
+			t.Errorf("app.Run gave %v, wanted %v", exitCode, ExitCodeSuccess)
+		}
+
+		if got, want := out.String(), "injected  "+workDir+"\n"; got != want {
+			t.Errorf("app.Run gave out %q, wanted %q", got, want)
+		}
+	})
+
+	t.Run("killed by a signal", func(t *testing.T) {
+		var errOut bytes.Buffer
+
+		app := newApp(true, io.Discard, &errOut)
+
+		if exitCode := app.Run(context.TODO(), []string{"killed"}); exitCode != ExitCodeError {
+			t.Errorf("app.Run gave %v, wanted %v", exitCode, ExitCodeError)
+		}
+
+		if want := "Error: unable to run plugin 'killed': signal: killed\n"; errOut.String() != want {
+			t.Errorf("app.Run gave errOut %q, wanted %q", errOut.String(), want)
+		}
+	})
+
+	t.Run("propagates exit codes and I/O", func(t *testing.T) {
+		var errOut bytes.Buffer
+
+		app := newApp(true, io.Discard, &errOut)
+		app.SetInput(strings.NewReader("purpose\n"))
+
+		if exitCode := app.Run(context.TODO(), []string{"fail"}); exitCode != 3 {
+			t.Errorf("app.Run gave %v, wanted %v", exitCode, 3)
+		}
+
+		if got, want := errOut.String(), "failing on purpose\n"; got != want {
+			t.Errorf("app.Run gave errOut %q, wanted %q", got, want)
+		}
+	})
+
+	t.Run("lists plugins in help", func(t *testing.T) {
+		var errOut bytes.Buffer
+
+		newApp(true, io.Discard, &errOut).PrintHelp("")
+
+		want := "\nCommands:\n\n\tdeploy\t\n\nPlugin commands:\n\n\tenv\n\tfail\n\tgreet\n\tkilled\n\nOptions:"
+		if !strings.Contains(errOut.String(), want) {
+			t.Errorf("app.PrintHelp gave %q, wanted it to contain %q", errOut.String(), want)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		var errOut bytes.Buffer
+
+		app := newApp(false, io.Discard, &errOut)
+
+		if exitCode := app.Run(context.TODO(), []string{"greet"}); exitCode != ExitCodeError {
+			t.Errorf("app.Run gave %v, wanted %v", exitCode, ExitCodeError)
+		}
+
+		if want := "Error: unknown command 'greet'"; !strings.Contains(errOut.String(), want) {
+			t.Errorf("app.Run gave errOut %q, wanted it to contain %q", errOut.String(), want)
+		}
+
+		errOut.Reset()
+		app.PrintHelp("")
+
+		if strings.Contains(errOut.String(), "Plugin commands:") {
+			t.Errorf("app.PrintHelp gave %q, wanted no plugin commands", errOut.String())
+		}
+	})
+
+	t.Run("not executable", func(t *testing.T) {
+		app := newApp(true, io.Discard, io.Discard)
+
+		if exitCode := app.Run(context.TODO(), []string{"data"}); exitCode != ExitCodeError {
+			t.Errorf("app.Run gave %v, wanted %v", exitCode, ExitCodeError)
+		}
+	})
+}
@@ -0,0 +1,176 @@
+// Copyright © 2026 Trevor N. Suarez (Rican7)
+
+package lieut
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// responseFilePrefix is the prefix of an argument that refers to a response
+// file, such as `@args.txt`. A doubled prefix escapes a literal prefix.
+const responseFilePrefix = "@"
+
+// maxResponseFileDepth is the maximum depth of response files referring to
+// other response files, to guard against infinite recursion.
+const maxResponseFileDepth = 10
+
+// expandResponseFiles replaces each response file argument with the arguments
+// contained in the file, recursively.
+//
+// Relative paths are resolved against the given directory, or the process'
+// working directory if it's empty. Arguments after a terminator (`--`) are left
+// as they are, so that literal arguments starting with the prefix can be
+// passed.
+func expandResponseFiles(arguments []string, dir string) ([]string, error) {
+	expanded, _, err := expandResponseFilesAtDepth(arguments, dir, 0)
+
+	return expanded, err
+}
+
+// expandResponseFilesAtDepth expands the response files in the given arguments,
+// and returns whether a terminator was reached.
+func expandResponseFilesAtDepth(arguments []string, dir string, depth int) ([]string, bool, error) {
+	expanded := make([]string, 0, len(arguments))
+
+	for i, argument := range arguments {
+		switch {
+		case argument == argumentTerminator:
+			return append(expanded, arguments[i:]...), true, nil
+		case !strings.HasPrefix(argument, responseFilePrefix) || argument == responseFilePrefix:
+			expanded = append(expanded, argument)
+		case strings.HasPrefix(argument, responseFilePrefix+responseFilePrefix):
+			// Unescape the literal prefix
+			expanded = append(expanded, argument[len(responseFilePrefix):])
+		default:
+			if depth >= maxResponseFileDepth {
+				err := fmt.Errorf("response files are nested more than %d levels deep", maxResponseFileDepth)
+
+				return nil, false, err
+			}
+
+			path := argument[len(responseFilePrefix):]
+
+			if dir != "" && !filepath.IsAbs(path) {
+				path = filepath.Join(dir, path)
+			}
+
+			contents, err := os.ReadFile(path)
+			if err != nil {
+				return nil, false, fmt.Errorf("unable to read response file: %w", err)
+			}
+
+			fileArguments, err := tokenizeArguments(string(contents))
+			if err != nil {
+				return nil, false, fmt.Errorf("invalid response file '%s': %w", path, err)
+			}
+
+			fileArguments, terminated, err := expandResponseFilesAtDepth(fileArguments, dir, depth+1)
+			if err != nil {
+				return nil, false, err
+			}
+
+			expanded = append(expanded, fileArguments...)
+
+			// A terminator within the file applies to the arguments after it
+			if terminated {
+				return append(expanded, arguments[i+1:]...), true, nil
+			}
+		}
+	}
+
+	return expanded, false, nil
+}
+
+// tokenizeArguments splits the given string into arguments, in the style of a
+// POSIX shell.
+//
+// Arguments are separated by whitespace. Single quotes preserve the literal
+// value of each character within them, while double quotes do the same except
+// for backslash escapes of `"` and `\`. Outside of quotes, a backslash escapes
+// the next character, and a `#` at the start of an argument begins a comment
+// that runs until the end of the line.
+func tokenizeArguments(s string) ([]string, error) {
+	var arguments []string
+	var current strings.Builder
+
+	inArgument := false
+	runes := []rune(s)
+
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+
+		switch {
+		case unicode.IsSpace(r):
+			if inArgument {
+				arguments = append(arguments, current.String())
+				current.Reset()
+				inArgument = false
+			}
+		case r == '#' && !inArgument:
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+		case r == '\\':
+			i++
+			if i >= len(runes) {
+				return nil, errors.New("unterminated escape")
+			}
+
+			// An escaped newline continues the line
+			if runes[i] != '\n' {
+				current.WriteRune(runes[i])
+				inArgument = true
+			}
+		case r == '\'':
+			end := indexRune(runes, i+1, '\'')
+			if end < 0 {
+				return nil, errors.New("unterminated single quote")
+			}
+
+			current.WriteString(string(runes[i+1 : end]))
+			inArgument = true
+			i = end
+		case r == '"':
+			i++
+			for ; i < len(runes) && runes[i] != '"'; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) && (runes[i+1] == '"' || runes[i+1] == '\\') {
+					i++
+				}
+
+				current.WriteRune(runes[i])
+			}
+
+			if i >= len(runes) {
+				return nil, errors.New("unterminated double quote")
+			}
+
+			inArgument = true
+		default:
+			current.WriteRune(r)
+			inArgument = true
+		}
+	}
+
+	if inArgument {
+		arguments = append(arguments, current.String())
+	}
+
+	return arguments, nil
+}
+
+// indexRune returns the index of the first instance of the given rune in the
+// given runes, starting at the given index, or -1 if it isn't present.
+func indexRune(runes []rune, start int, r rune) int {
+	for i := start; i < len(runes); i++ {
+		if runes[i] == r {
+			return i
+		}
+	}
+
+	return -1
+}
@@ -0,0 +1,162 @@
+package lieut
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTokenizeArguments(t *testing.T) {
+	for testName, testData := range map[string]struct {
+		input   string
+		want    []string
+		wantErr string
+	}{
+		"empty": {
+			input: "",
+			want:  nil,
+		},
+		"whitespace separated": {
+			input: "  a b\tc\n\nd  ",
+			want:  []string{"a", "b", "c", "d"},
+		},
+		"single quotes": {
+			input: `'a b' 'c\d' x'y'z`,
+			want:  []string{"a b", `c\d`, "xyz"},
+		},
+		"double quotes": {
+			input: `"a b" "c \"d\" \\ \e" ""`,
+			want:  []string{"a b", `c "d" \ \e`, ""},
+		},
+		"backslash escapes": {
+			input: `a\ b c\\d \'e`,
+			want:  []string{"a b", `c\d`, "'e"},
+		},
+		"escaped newline": {
+			input: "a\\\nb c",
+			want:  []string{"ab", "c"},
+		},
+		"comments": {
+			input: "# a comment\na # another comment\nb#not-a-comment '#quoted'",
+			want:  []string{"a", "b#not-a-comment", "#quoted"},
+		},
+		"unterminated single quote": {
+			input:   "'a b",
+			wantErr: "unterminated single quote",
+		},
+		"unterminated double quote": {
+			input:   `"a b`,
+			wantErr: "unterminated double quote",
+		},
+		"unterminated escape": {
+			input:   `a\`,
+			wantErr: "unterminated escape",
+		},
+	} {
+		t.Run(testName, func(t *testing.T) {
+			got, err := tokenizeArguments(testData.input)
+
+			if testData.wantErr != "" {
+				if err == nil || err.Error() != testData.wantErr {
+					t.Errorf("tokenizeArguments gave error %v, want %q", err, testData.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("tokenizeArguments returned error: %v", err)
+			}
+
+			if !reflect.DeepEqual(got, testData.want) {
+				t.Errorf("tokenizeArguments gave %q, want %q", got, testData.want)
+			}
+		})
+	}
+}
+
+func TestExpandResponseFiles(t *testing.T) {
+	dir := t.TempDir()
+
+	writeFile := func(name string, contents string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+			t.Fatalf("os.WriteFile returned error: %v", err)
+		}
+
+		return path
+	}
+
+	nested := writeFile("nested.txt", "c 'd e'")
+	outer := writeFile("outer.txt", "# Arguments\n-v a\n@"+nested+"\n@@literal")
+	terminated := writeFile("terminated.txt", "a -- @"+nested)
+	invalid := writeFile("invalid.txt", "'unterminated")
+	loop := filepath.Join(dir, "loop.txt")
+	writeFile("loop.txt", "@"+loop)
+	writeFile("relative.txt", "-v @nested.txt")
+
+	for testName, testData := range map[string]struct {
+		arguments []string
+		dir       string
+		want      []string
+		wantErr   string
+	}{
+		"no response files": {
+			arguments: []string{"-v", "a", "@"},
+			want:      []string{"-v", "a", "@"},
+		},
+		"escaped prefix": {
+			arguments: []string{"@@user", "@@@double"},
+			want:      []string{"@user", "@@double"},
+		},
+		"nested response files": {
+			arguments: []string{"first", "@" + outer, "last"},
+			want:      []string{"first", "-v", "a", "c", "d e", "@literal", "last"},
+		},
+		"terminated arguments": {
+			arguments: []string{"@" + nested, "--", "@" + nested, "@@user"},
+			want:      []string{"c", "d e", "--", "@" + nested, "@@user"},
+		},
+		"terminated response file": {
+			arguments: []string{"@" + terminated, "@" + nested},
+			want:      []string{"a", "--", "@" + nested, "@" + nested},
+		},
+		"relative to the directory": {
+			arguments: []string{"@relative.txt", "@" + nested},
+			dir:       dir,
+			want:      []string{"-v", "c", "d e", "c", "d e"},
+		},
+		"missing file": {
+			arguments: []string{"@" + filepath.Join(dir, "missing.txt")},
+			wantErr:   "unable to read response file",
+		},
+		"invalid file": {
+			arguments: []string{"@" + invalid},
+			wantErr:   "invalid response file '" + invalid + "': unterminated single quote",
+		},
+		"recursive file": {
+			arguments: []string{"@" + loop},
+			wantErr:   "response files are nested more than 10 levels deep",
+		},
+	} {
+		t.Run(testName, func(t *testing.T) {
+			got, err := expandResponseFiles(testData.arguments, testData.dir)
+
+			if testData.wantErr != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), testData.wantErr) {
+					t.Errorf("expandResponseFiles gave error %v, want %q", err, testData.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("expandResponseFiles returned error: %v", err)
+			}
+
+			if !reflect.DeepEqual(got, testData.want) {
+				t.Errorf("expandResponseFiles gave %q, want %q", got, testData.want)
+			}
+		})
+	}
+}
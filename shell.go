@@ -0,0 +1,412 @@
+// Copyright © 2026 Trevor N. Suarez (Rican7)
+
+package lieut
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// DefaultShellPrompt defines the default prompt template of the shell mode.
+const DefaultShellPrompt = "{{.Name}}> "
+
+// Shell built-in command names.
+const (
+	shellExit    = "exit"
+	shellHelp    = "help"
+	shellHistory = "history"
+)
+
+// ShellOptions defines options for running an app in shell mode.
+type ShellOptions struct {
+	// Prompt is the text/template of the prompt displayed before each line is
+	// read, which is given a ShellPromptData. Defaults to DefaultShellPrompt.
+	Prompt string
+
+	// HistoryFile is the path of the file that entered lines are appended to,
+	// and that the history is loaded from when the shell starts, if set.
+	HistoryFile string
+
+	// ReadLine reads a line of input after displaying the given prompt, for
+	// integrating a line editor, which can offer completions via the given
+	// complete function. It should return io.EOF when there's no more input.
+	//
+	// By default, lines are read from the app's input, and the prompt is
+	// written to the app's error output.
+	ReadLine func(prompt string, complete func(line string) []string) (string, error)
+}
+
+// ShellPromptData is the data given to the shell's prompt template.
+type ShellPromptData struct {
+	Name    string
+	Version string
+
+	// ExitCode is the exit code of the previous line.
+	ExitCode int
+}
+
+// RunShell runs the app in an interactive shell mode, and returns the exit code
+// of the last line run.
+//
+// Each line read is split into arguments in the style of a POSIX shell, and
+// then run through the normal Run pipeline (flag parsing, the init function,
+// error handling, etc), without exiting on errors. Flags keep their values
+// between lines, unless they're built per run (see NewMultiCommandAppFunc and
+// SetCommandFunc). The shell stops when there's no more input, or when the
+// `exit [code]` built-in is entered. The `help [command]` and `history`
+// built-ins display help and the history of entered lines, respectively.
+// Commands (and aliases) take precedence over any built-ins of the same name.
+//
+// While the shell is running, an interrupt signal only cancels the context of
+// the running command, rather than stopping the shell.
+func (a *MultiCommandApp) RunShell(ctx context.Context, options ShellOptions) int {
+	// Use a copy of the app, so that any environment only applies to the shell
+	r := *a
+	r.useEnvironment(ctx)
+
+	return r.runShell(ctx, a, options)
+}
+
+func (a *MultiCommandApp) runShell(ctx context.Context, runner *MultiCommandApp, options ShellOptions) int {
+	promptText := options.Prompt
+	if promptText == "" {
+		promptText = DefaultShellPrompt
+	}
+
+	prompt, err := template.New("prompt").Parse(promptText)
+	if err != nil {
+		a.printError(fmt.Errorf("invalid shell prompt: %w", err))
+		return ExitCodeError
+	}
+
+	history, err := loadShellHistory(options.HistoryFile)
+	if err != nil {
+		a.printError(err)
+		return ExitCodeError
+	}
+
+	readLine := options.ReadLine
+	if readLine == nil {
+		readLine = a.shellLineReader()
+	}
+
+	// Keep interrupts from stopping the shell, leaving them to cancel commands
+	if a.env == nil {
+		interrupts := make(chan os.Signal, 1)
+		signal.Notify(interrupts, os.Interrupt)
+		defer stopSignals(interrupts)
+	}
+
+	exitCode := ExitCodeSuccess
+
+	for ctx.Err() == nil {
+		var promptBuffer strings.Builder
+		_ = prompt.Execute(&promptBuffer, ShellPromptData{
+			Name:     a.info.Name,
+			Version:  a.info.Version,
+			ExitCode: exitCode,
+		})
+
+		line, err := readLine(promptBuffer.String(), a.completeShell)
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				a.printError(err)
+				return ExitCodeError
+			}
+
+			return exitCode
+		}
+
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		history = append(history, line)
+		if err := appendShellHistory(options.HistoryFile, line); err != nil {
+			a.printError(err)
+		}
+
+		arguments, err := tokenizeArguments(line)
+		if err != nil {
+			a.printError(err)
+			exitCode = ExitCodeUsageError
+			continue
+		}
+
+		if len(arguments) == 0 {
+			continue
+		}
+
+		switch name := arguments[0]; {
+		case a.hasCommandOrAlias(name):
+			exitCode = runner.Run(ctx, arguments)
+		case name == shellExit:
+			if len(arguments) > 1 {
+				if exitCode, err = strconv.Atoi(arguments[1]); err != nil {
+					a.printError(fmt.Errorf("invalid exit code '%s'", arguments[1]))
+					return ExitCodeUsageError
+				}
+			}
+
+			return exitCode
+		case name == shellHelp:
+			commandName := ""
+			if len(arguments) > 1 {
+				commandName = arguments[1]
+			}
+
+			a.PrintHelp(commandName)
+			exitCode = ExitCodeSuccess
+		case name == shellHistory:
+			for i, entry := range history {
+				fmt.Fprintf(a.out, "%5d  %s\n", i+1, entry)
+			}
+
+			exitCode = ExitCodeSuccess
+		default:
+			exitCode = runner.Run(ctx, arguments)
+		}
+	}
+
+	return exitCode
+}
+
+// hasCommandOrAlias returns whether the given name is the name of a command or
+// an alias, which take precedence over the shell's built-ins.
+func (a *MultiCommandApp) hasCommandOrAlias(name string) bool {
+	_, hasCommand := a.commands[name]
+	_, hasAlias := a.aliases[name]
+
+	return hasCommand || hasAlias
+}
+
+// stopSignals stops relaying signals to the given channel, and drains any
+// signals that were relayed but not received.
+func stopSignals(signals chan os.Signal) {
+	signal.Stop(signals)
+
+	for {
+		select {
+		case <-signals:
+		default:
+			return
+		}
+	}
+}
+
+// shellLineReader returns a function that reads lines from the app's input,
+// writing the prompt to the app's error output.
+func (a *app) shellLineReader() func(string, func(string) []string) (string, error) {
+	in := a.in
+	if in == nil {
+		in = os.Stdin
+	}
+
+	scanner := bufio.NewScanner(in)
+
+	return func(prompt string, _ func(string) []string) (string, error) {
+		fmt.Fprint(a.errOut, prompt)
+
+		if !scanner.Scan() {
+			if err := scanner.Err(); err != nil {
+				return "", err
+			}
+
+			return "", io.EOF
+		}
+
+		return scanner.Text(), nil
+	}
+}
+
+// completeShell returns the completions of the given line in shell mode, which
+// include the shell's built-ins.
+func (a *MultiCommandApp) completeShell(line string) []string {
+	completions := a.Complete(line)
+
+	words, current := splitCompletionLine(line)
+	if len(words) == 0 {
+		for _, builtIn := range []string{shellExit, shellHelp, shellHistory} {
+			if strings.HasPrefix(builtIn, current) && !a.hasCommandOrAlias(builtIn) {
+				completions = append(completions, builtIn)
+			}
+		}
+
+		sort.Strings(completions)
+	}
+
+	return completions
+}
+
+// Complete returns the possible completions of the last word of the given
+// (partial) command line, such as for a line editor or a shell's completion.
+//
+// Completions include the names of visible commands and aliases, the names of
+// visible flags of the command (or the global flags), and the choices of a
+// flag's value (for flags that have choices, like those defined with
+// EnumValue).
+func (a *MultiCommandApp) Complete(line string) []string {
+	words, current := splitCompletionLine(line)
+
+	var candidates []string
+
+	switch {
+	case len(words) == 0 && !isFlagArgument(current):
+		for _, name := range a.commandNames {
+			if !a.commands[name].info.Hidden {
+				candidates = append(candidates, name)
+			}
+		}
+
+		for _, name := range a.aliasNames {
+			if _, hasCommand := a.commands[name]; !hasCommand {
+				candidates = append(candidates, name)
+			}
+		}
+	default:
+		flags := a.flags
+		if len(words) > 0 {
+			if command, hasCommand := a.commands[words[0]]; hasCommand {
+				flags = command.flags
+			}
+		}
+
+		candidates = completeFlags(flags.Flags, words, current)
+	}
+
+	var completions []string
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, current) {
+			completions = append(completions, candidate)
+		}
+	}
+
+	sort.Strings(completions)
+
+	return completions
+}
+
+// completeFlags returns the candidate completions of the given current word,
+// for the given flags and preceding words.
+func completeFlags(flags Flags, words []string, current string) []string {
+	dashPrefix := flagDashPrefix(flags)
+
+	var infos []flagInfo
+	visitFlags(flags, func(f flagInfo) {
+		if !f.hidden {
+			infos = append(infos, f)
+		}
+	})
+
+	choicesOf := func(argument string) ([]string, bool) {
+		name := strings.TrimLeft(argument, "-")
+
+		for _, f := range infos {
+			if f.name == name && len(f.choices) > 0 {
+				return f.choices, true
+			}
+		}
+
+		return nil, false
+	}
+
+	// Complete the value of a flag, given as `--flag value`
+	if len(words) > 0 && isFlagArgument(words[len(words)-1]) && !strings.Contains(words[len(words)-1], "=") {
+		if choices, ok := choicesOf(words[len(words)-1]); ok {
+			return choices
+		}
+	}
+
+	// Complete the value of a flag, given as `--flag=value`
+	if flagArgument, _, hasValue := strings.Cut(current, "="); hasValue && isFlagArgument(flagArgument) {
+		choices, _ := choicesOf(flagArgument)
+
+		var candidates []string
+		for _, choice := range choices {
+			candidates = append(candidates, flagArgument+"="+choice)
+		}
+
+		return candidates
+	}
+
+	if !isFlagArgument(current) {
+		return nil
+	}
+
+	var candidates []string
+	for _, f := range infos {
+		candidates = append(candidates, dashPrefix+f.name)
+
+		if f.negatable {
+			candidates = append(candidates, dashPrefix+"no-"+f.name)
+		}
+	}
+
+	return candidates
+}
+
+// splitCompletionLine splits the given line into its complete words and the
+// current (last, possibly empty) word being completed.
+func splitCompletionLine(line string) (words []string, current string) {
+	words = strings.Fields(line)
+
+	if len(words) == 0 || strings.HasSuffix(line, " ") || strings.HasSuffix(line, "\t") {
+		return words, ""
+	}
+
+	return words[:len(words)-1], words[len(words)-1]
+}
+
+// loadShellHistory loads the history of the shell from the given file, if set.
+func loadShellHistory(path string) ([]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("unable to read shell history: %w", err)
+	}
+
+	var history []string
+	for _, line := range strings.Split(string(contents), "\n") {
+		if line != "" {
+			history = append(history, line)
+		}
+	}
+
+	return history, nil
+}
+
+// appendShellHistory appends the given line to the given history file, if set.
+func appendShellHistory(path string, line string) error {
+	if path == "" {
+		return nil
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("unable to write shell history: %w", err)
+	}
+
+	if _, err := fmt.Fprintln(file, line); err != nil {
+		file.Close()
+		return fmt.Errorf("unable to write shell history: %w", err)
+	}
+
+	return file.Close()
+}
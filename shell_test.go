@@ -0,0 +1,262 @@
+package lieut
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func newTestShellApp(t *testing.T, out io.Writer, errOut io.Writer) *MultiCommandApp {
+	t.Helper()
+
+	app := NewMultiCommandApp(AppInfo{Name: "test", Version: "vTest"}, nil, out, errOut)
+
+	var region string
+	deployFlags := flag.NewFlagSet("deploy", flag.ContinueOnError)
+	deployFlags.Bool("force", false, "Force it")
+	deployFlags.Bool("secret", false, "A hidden flag")
+	deployFlags.Var(EnumValue(&region, "us", "eu"), "region", "The region")
+
+	_ = MarkFlagHidden(deployFlags, "secret")
+	_ = MarkFlagNegatable(deployFlags, "force")
+
+	deploy := func(ctx context.Context, arguments []string) error {
+		_, err := io.WriteString(Stdout(ctx), "deployed "+strings.Join(arguments, ",")+"\n")
+		return err
+	}
+
+	fail := func(ctx context.Context, arguments []string) error {
+		return errors.New("failed")
+	}
+
+	_ = app.SetCommand(CommandInfo{Name: "deploy"}, deploy, deployFlags)
+	_ = app.SetCommand(CommandInfo{Name: "destroy"}, testNoOpExecutor, nil)
+	_ = app.SetCommand(CommandInfo{Name: "fail"}, fail, nil)
+	_ = app.SetCommand(CommandInfo{Name: "debug", Hidden: true}, testNoOpExecutor, nil)
+
+	return app
+}
+
+func TestMultiCommandApp_Complete(t *testing.T) {
+	app := newTestShellApp(t, io.Discard, io.Discard)
+
+	for testName, testData := range map[string]struct {
+		line string
+		want []string
+	}{
+		"all commands": {
+			line: "",
+			want: []string{"deploy", "destroy", "fail"},
+		},
+		"command prefix": {
+			line: "de",
+			want: []string{"deploy", "destroy"},
+		},
+		"global flags": {
+			line: "-",
+			want: []string{"-help", "-version"},
+		},
+		"command flags": {
+			line: "deploy -",
+			want: []string{"-force", "-help", "-no-force", "-region"},
+		},
+		"command flag prefix": {
+			line: "deploy -f",
+			want: []string{"-force"},
+		},
+		"flag choices": {
+			line: "deploy -region ",
+			want: []string{"eu", "us"},
+		},
+		"flag choices with equals": {
+			line: "deploy -region=e",
+			want: []string{"-region=eu"},
+		},
+		"arguments": {
+			line: "deploy arg",
+			want: nil,
+		},
+	} {
+		t.Run(testName, func(t *testing.T) {
+			if got := app.Complete(testData.line); !reflect.DeepEqual(got, testData.want) {
+				t.Errorf("app.Complete(%q) gave %q, wanted %q", testData.line, got, testData.want)
+			}
+		})
+	}
+}
+
+func TestMultiCommandApp_RunShell(t *testing.T) {
+	var out, errOut bytes.Buffer
+
+	historyFile := filepath.Join(t.TempDir(), "history")
+	if err := os.WriteFile(historyFile, []byte("destroy\n"), 0o600); err != nil {
+		t.Fatalf("os.WriteFile returned error: %v", err)
+	}
+
+	app := newTestShellApp(t, &out, &errOut)
+	app.SetInput(strings.NewReader(strings.Join([]string{
+		"deploy 'first arg' second",
+		"",
+		"# a comment",
+		"fail",
+		"deploy -unknown",
+		"bogus",
+		"help deploy",
+		"history",
+		"exit 3",
+		"deploy never",
+	}, "\n")))
+
+	exitCode := app.RunShell(context.TODO(), ShellOptions{
+		Prompt:      "{{.Name}} [{{.ExitCode}}]$ ",
+		HistoryFile: historyFile,
+	})
+
+	if exitCode != 3 {
+		t.Errorf("app.RunShell gave %v, wanted %v", exitCode, 3)
+	}
+
+	wantOut := "deployed first arg,second\n" +
+		"    1  destroy\n" +
+		"    2  deploy 'first arg' second\n" +
+		"    3  # a comment\n" +
+		"    4  fail\n" +
+		"    5  deploy -unknown\n" +
+		"    6  bogus\n" +
+		"    7  help deploy\n" +
+		"    8  history\n"
+
+	if out.String() != wantOut {
+		t.Errorf("app.RunShell gave out %q, wanted %q", out.String(), wantOut)
+	}
+
+	for _, want := range []string{
+		"test [0]$ test [0]$ test [0]$ test [0]$ Error: failed\n",
+		"test [1]$ Error: flag provided but not defined: -unknown\n",
+		"test [2]$ Error: unknown command 'bogus'\n",
+		"test [1]$ Usage: test deploy",
+		"test [0]$ test [0]$ ",
+	} {
+		if !strings.Contains(errOut.String(), want) {
+			t.Errorf("app.RunShell gave errOut %q, wanted it to contain %q", errOut.String(), want)
+		}
+	}
+
+	history, err := os.ReadFile(historyFile)
+	if err != nil {
+		t.Fatalf("os.ReadFile returned error: %v", err)
+	}
+
+	want := "destroy\ndeploy 'first arg' second\n"
+	if !strings.HasPrefix(string(history), want) || !strings.HasSuffix(string(history), "exit 3\n") {
+		t.Errorf("app.RunShell wrote history %q, wanted it to start with %q and end with %q", history, want, "exit 3\n")
+	}
+}
+
+func TestMultiCommandApp_RunShell_FlagsPerLine(t *testing.T) {
+	var out bytes.Buffer
+
+	app := NewMultiCommandApp(AppInfo{Name: "test"}, nil, &out, io.Discard)
+
+	_ = app.SetCommandFunc(CommandInfo{Name: "deploy"}, func() (Flags, Executor, error) {
+		flags := flag.NewFlagSet("deploy", flag.ContinueOnError)
+		force := flags.Bool("force", false, "Force it")
+
+		deploy := func(ctx context.Context, arguments []string) error {
+			_, err := fmt.Fprintf(Stdout(ctx), "force: %t\n", *force)
+			return err
+		}
+
+		return flags, deploy, nil
+	})
+	app.SetInput(strings.NewReader("deploy -force\ndeploy\n"))
+
+	if exitCode := app.RunShell(context.TODO(), ShellOptions{}); exitCode != ExitCodeSuccess {
+		t.Errorf("app.RunShell gave %v, wanted %v", exitCode, ExitCodeSuccess)
+	}
+
+	if want := "force: true\nforce: false\n"; out.String() != want {
+		t.Errorf("app.RunShell gave out %q, wanted %q", out.String(), want)
+	}
+}
+
+func TestMultiCommandApp_RunShell_CommandsOverrideBuiltIns(t *testing.T) {
+	var out bytes.Buffer
+
+	app := NewMultiCommandApp(AppInfo{Name: "test"}, nil, &out, io.Discard)
+
+	help := func(ctx context.Context, arguments []string) error {
+		_, err := io.WriteString(Stdout(ctx), "custom help "+strings.Join(arguments, ",")+"\n")
+		return err
+	}
+
+	_ = app.SetCommand(CommandInfo{Name: "help"}, help, nil)
+	app.SetInput(strings.NewReader("help deploy\n"))
+
+	if exitCode := app.RunShell(context.TODO(), ShellOptions{}); exitCode != ExitCodeSuccess {
+		t.Errorf("app.RunShell gave %v, wanted %v", exitCode, ExitCodeSuccess)
+	}
+
+	if want := "custom help deploy\n"; out.String() != want {
+		t.Errorf("app.RunShell gave out %q, wanted %q", out.String(), want)
+	}
+
+	if got := app.completeShell("h"); !reflect.DeepEqual(got, []string{"help", "history"}) {
+		t.Errorf("app.completeShell gave %q, wanted %q", got, []string{"help", "history"})
+	}
+}
+
+func TestMultiCommandApp_RunShell_ReadLine(t *testing.T) {
+	app := newTestShellApp(t, io.Discard, io.Discard)
+
+	lines := []string{"de", "fail"}
+	var completions [][]string
+
+	readLine := func(prompt string, complete func(line string) []string) (string, error) {
+		if len(lines) == 0 {
+			return "", io.EOF
+		}
+
+		line := lines[0]
+		lines = lines[1:]
+
+		completions = append(completions, complete(line))
+
+		return line, nil
+	}
+
+	if exitCode := app.RunShell(context.TODO(), ShellOptions{ReadLine: readLine}); exitCode != ExitCodeError {
+		t.Errorf("app.RunShell gave %v, wanted %v", exitCode, ExitCodeError)
+	}
+
+	want := [][]string{{"deploy", "destroy"}, {"fail"}}
+	if !reflect.DeepEqual(completions, want) {
+		t.Errorf("app.RunShell completed %q, wanted %q", completions, want)
+	}
+
+	if got := app.completeShell("h"); !reflect.DeepEqual(got, []string{"help", "history"}) {
+		t.Errorf("app.completeShell gave %q, wanted %q", got, []string{"help", "history"})
+	}
+}
+
+func TestMultiCommandApp_RunShell_InvalidPrompt(t *testing.T) {
+	var errOut bytes.Buffer
+
+	app := newTestShellApp(t, io.Discard, &errOut)
+
+	if exitCode := app.RunShell(context.TODO(), ShellOptions{Prompt: "{{"}); exitCode != ExitCodeError {
+		t.Errorf("app.RunShell gave %v, wanted %v", exitCode, ExitCodeError)
+	}
+
+	if want := "Error: invalid shell prompt: "; !strings.HasPrefix(errOut.String(), want) {
+		t.Errorf("app.RunShell gave errOut %q, wanted prefix %q", errOut.String(), want)
+	}
+}
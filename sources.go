@@ -0,0 +1,108 @@
+// Copyright © 2026 Trevor N. Suarez (Rican7)
+
+package lieut
+
+import (
+	"context"
+	"fmt"
+)
+
+// FlagSource describes where the effective value of a flag came from.
+type FlagSource int
+
+// Flag sources.
+const (
+	// FlagSourceDefault is the source of a flag that wasn't set, and that holds
+	// its default value.
+	FlagSourceDefault FlagSource = iota
+
+	// FlagSourceCommandLine is the source of a flag that was set from the
+	// command line arguments.
+	FlagSourceCommandLine
+
+	// FlagSourceEnvironment is the source of a flag that was set from its
+	// environment variable (see SetFlagEnv).
+	FlagSourceEnvironment
+)
+
+// String returns the name of the source.
+func (s FlagSource) String() string {
+	switch s {
+	case FlagSourceDefault:
+		return "default"
+	case FlagSourceCommandLine:
+		return "command line"
+	case FlagSourceEnvironment:
+		return "environment"
+	default:
+		return fmt.Sprintf("source(%d)", int(s))
+	}
+}
+
+// FlagState describes the effective value of a flag for a run, and its source.
+type FlagState struct {
+	Name   string
+	Value  string
+	Source FlagSource
+}
+
+// IsSet returns whether the flag was set, from any source, rather than holding
+// its default value.
+func (s FlagState) IsSet() bool {
+	return s.Source != FlagSourceDefault
+}
+
+// FlagStatesFromContext returns the states of the flags of the running command
+// (including any global flags), from the context given to an Executor.
+//
+// It returns nil if the context isn't from a run.
+func FlagStatesFromContext(ctx context.Context) []FlagState {
+	states, _ := ctx.Value(flagStatesContextKey).([]FlagState)
+
+	return append([]FlagState(nil), states...)
+}
+
+// FlagStateFromContext returns the state of the named flag of the running
+// command, from the context given to an Executor.
+//
+// It returns false if the flag isn't defined, or if the context isn't from a
+// run.
+func FlagStateFromContext(ctx context.Context, name string) (FlagState, bool) {
+	states, _ := ctx.Value(flagStatesContextKey).([]FlagState)
+
+	for _, state := range states {
+		if state.Name == name {
+			return state, true
+		}
+	}
+
+	return FlagState{}, false
+}
+
+// withFlagStates returns a copy of the given context that holds the states of
+// the given parsed flags, given the names of the flags set from the
+// environment.
+func withFlagStates(ctx context.Context, flags Flags, fromEnv map[string]bool) context.Context {
+	flags = unwrapFlags(flags)
+
+	isSet := make(map[string]bool)
+	visitSetFlags(flags, func(f flagInfo) {
+		isSet[f.name] = true
+	})
+
+	var states []FlagState
+	visitFlags(flags, func(f flagInfo) {
+		state := FlagState{Name: f.name, Value: f.value}
+
+		switch {
+		case fromEnv[f.name]:
+			state.Source = FlagSourceEnvironment
+		case isSet[f.name]:
+			state.Source = FlagSourceCommandLine
+		}
+
+		states = append(states, state)
+	})
+
+	return context.WithValue(ctx, flagStatesContextKey, states)
+}
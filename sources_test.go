@@ -0,0 +1,152 @@
+package lieut
+
+import (
+	"context"
+	"flag"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestFlagSource_String(t *testing.T) {
+	for source, want := range map[FlagSource]string{
+		FlagSourceDefault:     "default",
+		FlagSourceCommandLine: "command line",
+		FlagSourceEnvironment: "environment",
+		FlagSource(9):         "source(9)",
+	} {
+		if got := source.String(); got != want {
+			t.Errorf("FlagSource(%d).String gave %q, want %q", int(source), got, want)
+		}
+	}
+}
+
+func TestFlagStatesFromContext(t *testing.T) {
+	t.Setenv("LIEUT_TEST_REGION", "eu")
+
+	globalFlags := flag.NewFlagSet(testAppInfo.Name, flag.ContinueOnError)
+	globalFlags.Bool("verbose", false, "Enable verbose output")
+
+	commandFlags := flag.NewFlagSet("deploy", flag.ContinueOnError)
+	commandFlags.String("region", "us", "The region")
+	commandFlags.String("timezone", "UTC", "The timezone")
+	commandFlags.Bool("color", true, "Colorize the output")
+
+	_ = SetFlagEnv(commandFlags, "region", "LIEUT_TEST_REGION")
+	_ = MarkFlagNegatable(commandFlags, "color")
+
+	var states []FlagState
+	var timezone FlagState
+	var hasTimezone, hasUndefined bool
+
+	executor := func(ctx context.Context, arguments []string) error {
+		states = FlagStatesFromContext(ctx)
+		timezone, hasTimezone = FlagStateFromContext(ctx, "timezone")
+		_, hasUndefined = FlagStateFromContext(ctx, "undefined")
+		return nil
+	}
+
+	app := NewMultiCommandApp(testAppInfo, globalFlags, io.Discard, io.Discard)
+	_ = app.SetCommand(CommandInfo{Name: "deploy"}, executor, commandFlags)
+
+	if exitCode := app.Run(context.TODO(), []string{"deploy", "-verbose", "-no-color"}); exitCode != ExitCodeSuccess {
+		t.Fatalf("app.Run gave %v, wanted %v", exitCode, ExitCodeSuccess)
+	}
+
+	want := []FlagState{
+		{Name: "color", Value: "false", Source: FlagSourceCommandLine},
+		{Name: "help", Value: "false", Source: FlagSourceDefault},
+		{Name: "region", Value: "eu", Source: FlagSourceEnvironment},
+		{Name: "timezone", Value: "UTC", Source: FlagSourceDefault},
+		{Name: "verbose", Value: "true", Source: FlagSourceCommandLine},
+	}
+
+	if !reflect.DeepEqual(states, want) {
+		t.Errorf("FlagStatesFromContext gave %+v, want %+v", states, want)
+	}
+
+	if !hasTimezone || timezone.IsSet() || timezone.Value != "UTC" {
+		t.Errorf("FlagStateFromContext gave %+v (%v), want an unset default", timezone, hasTimezone)
+	}
+
+	if hasUndefined {
+		t.Error("FlagStateFromContext returned true for an undefined flag")
+	}
+
+	if got := FlagStatesFromContext(context.TODO()); got != nil {
+		t.Errorf("FlagStatesFromContext gave %+v for a context without states, want nil", got)
+	}
+}
+
+func TestFlagStatesFromContext_Repeated(t *testing.T) {
+	var region FlagState
+	executor := func(ctx context.Context, arguments []string) error {
+		region, _ = FlagStateFromContext(ctx, "region")
+		return nil
+	}
+
+	app, err := NewSingleCommandAppFunc(testAppInfo, func() (Flags, Executor, error) {
+		flags := flag.NewFlagSet("deploy", flag.ContinueOnError)
+		flags.String("region", "us", "The region")
+		flags.String("token", "", "The token")
+
+		_ = SetFlagEnv(flags, "region", "REGION")
+		_ = MarkFlagRequired(flags, "token")
+
+		return flags, executor, nil
+	}, io.Discard, io.Discard)
+	if err != nil {
+		t.Fatalf("NewSingleCommandAppFunc returned error: %v", err)
+	}
+
+	for _, run := range []struct {
+		arguments []string
+		env       map[string]string
+
+		wantExitCode int
+		wantRegion   FlagState
+	}{
+		{
+			arguments: []string{"-region=eu", "-token=a"},
+			env:       map[string]string{"REGION": "ap"},
+
+			wantExitCode: ExitCodeSuccess,
+			wantRegion:   FlagState{Name: "region", Value: "eu", Source: FlagSourceCommandLine},
+		},
+		{
+			arguments: []string{"-token=b"},
+			env:       map[string]string{"REGION": "ap"},
+
+			wantExitCode: ExitCodeSuccess,
+			wantRegion:   FlagState{Name: "region", Value: "ap", Source: FlagSourceEnvironment},
+		},
+		{
+			arguments: []string{"-token=c"},
+
+			wantExitCode: ExitCodeSuccess,
+			wantRegion:   FlagState{Name: "region", Value: "us", Source: FlagSourceDefault},
+		},
+		{
+			arguments: []string{"-region=eu"},
+
+			wantExitCode: ExitCodeUsageError,
+		},
+	} {
+		region = FlagState{}
+
+		ctx := WithEnvironment(context.TODO(), Environment{
+			LookupEnv: func(key string) (string, bool) {
+				value, ok := run.env[key]
+				return value, ok
+			},
+		})
+
+		if exitCode := app.Run(ctx, run.arguments); exitCode != run.wantExitCode {
+			t.Errorf("app.Run(%q) gave %v, wanted %v", run.arguments, exitCode, run.wantExitCode)
+		}
+
+		if region != run.wantRegion {
+			t.Errorf("app.Run(%q) gave region %+v, wanted %+v", run.arguments, region, run.wantRegion)
+		}
+	}
+}
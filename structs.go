@@ -0,0 +1,586 @@
+// Copyright © 2026 Trevor N. Suarez (Rican7)
+
+package lieut
+
+import (
+	"encoding"
+	"errors"
+	"flag"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Struct tag keys, used to declare flags and arguments via struct fields.
+const (
+	tagFlag      = "flag"
+	tagShort     = "short"
+	tagUsage     = "usage"
+	tagEnv       = "env"
+	tagDefault   = "default"
+	tagRequired  = "required"
+	tagNegatable = "negatable"
+	tagArg       = "arg"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// structField is a tagged field of an options struct.
+type structField struct {
+	name  string
+	value reflect.Value
+	tag   reflect.StructTag
+}
+
+// StructFlags returns flags, with the given name, that are declared by the
+// tagged fields of the given options, which must be a pointer to a struct.
+//
+// Each field with a `flag` tag declares a flag of that name, which sets the
+// field when parsed. The following tags further describe the flag:
+//
+//   - `short`: a single-character shorthand for the flag (see SetFlagShorthand)
+//   - `usage`: the usage description of the flag
+//   - `env`: an environment variable to read when unset (see SetFlagEnv)
+//   - `default`: the default value of the flag
+//   - `required`: whether the flag must be set (see MarkFlagRequired)
+//   - `negatable`: whether a boolean flag is negatable (see MarkFlagNegatable)
+//
+// Fields may be strings, bools, ints, uints, floats, durations, slices of those
+// types, maps of strings (see StringMapValue), or any type that implements
+// flag.Value or encoding.TextUnmarshaler. A field's existing value is used as
+// its flag's default, unless the `default` tag is provided. Slice flags may be
+// repeated or given comma separated values, which replace the default.
+//
+// Embedded structs without tags have their fields declared as well, so that
+// groups of options can be shared. Fields with an `arg` tag are bound from the
+// positional arguments via BindArguments.
+func StructFlags(name string, options any) (*flag.FlagSet, error) {
+	fields, err := structFields(options)
+	if err != nil {
+		return nil, err
+	}
+
+	flags := createDefaultFlags(name)
+
+	for _, field := range fields {
+		if name, isFlag := field.tag.Lookup(tagFlag); !isFlag || name == "-" {
+			continue
+		}
+
+		if err := defineStructFlag(flags, field); err != nil {
+			return nil, err
+		}
+	}
+
+	return flags, nil
+}
+
+// BindArguments binds the given positional arguments to the fields of the given
+// options (a pointer to a struct) that have an `arg` tag, in field order.
+//
+// The tag's value names the argument in error messages. A slice field collects
+// all of the remaining arguments, so it must be the last argument field. Fields
+// with a `required:"true"` tag must be bound to an argument.
+//
+// Missing, invalid, or unexpected arguments result in an error that requests
+// help (see ErrWithHelpRequested), so it can be returned from an Executor as is.
+func BindArguments(options any, arguments []string) error {
+	argFields, err := argumentFields(options)
+	if err != nil {
+		return err
+	}
+
+	for i, field := range argFields {
+		name := field.tag.Get(tagArg)
+
+		required, err := isTrueTag(field, tagRequired)
+		if err != nil {
+			return err
+		}
+
+		if isSliceField(field.value) {
+			if i != len(argFields)-1 {
+				return fmt.Errorf("argument field '%s' must be the last argument field", field.name)
+			}
+
+			if required && len(arguments) == 0 {
+				return ErrWithHelpRequested(fmt.Errorf("missing required argument '%s'", name))
+			}
+
+			values := reflect.MakeSlice(field.value.Type(), 0, len(arguments))
+			for _, argument := range arguments {
+				elem := reflect.New(field.value.Type().Elem()).Elem()
+				if err := setScalar(elem, argument); err != nil {
+					err = fmt.Errorf("invalid value %q for argument '%s': %v", argument, name, err)
+					return ErrWithHelpRequested(err)
+				}
+
+				values = reflect.Append(values, elem)
+			}
+
+			field.value.Set(values)
+			arguments = nil
+			break
+		}
+
+		if len(arguments) == 0 {
+			if required {
+				return ErrWithHelpRequested(fmt.Errorf("missing required argument '%s'", name))
+			}
+
+			continue
+		}
+
+		if err := setScalar(field.value, arguments[0]); err != nil {
+			return ErrWithHelpRequested(fmt.Errorf("invalid value %q for argument '%s': %v", arguments[0], name, err))
+		}
+
+		arguments = arguments[1:]
+	}
+
+	if len(arguments) > 0 {
+		return ErrWithHelpRequested(fmt.Errorf("unexpected argument '%s'", arguments[0]))
+	}
+
+	return nil
+}
+
+// hasArgumentFields returns whether the given options have any argument fields.
+func hasArgumentFields(options any) (bool, error) {
+	argFields, err := argumentFields(options)
+
+	return len(argFields) > 0, err
+}
+
+// argumentFields returns the fields of the given options that have an `arg`
+// tag, in field order.
+func argumentFields(options any) ([]structField, error) {
+	fields, err := structFields(options)
+	if err != nil {
+		return nil, err
+	}
+
+	var argFields []structField
+	for _, field := range fields {
+		if _, isArg := field.tag.Lookup(tagArg); isArg {
+			argFields = append(argFields, field)
+		}
+	}
+
+	return argFields, nil
+}
+
+// structFields returns the tagged fields of the given options, including those
+// of any embedded structs.
+func structFields(options any) ([]structField, error) {
+	value := reflect.ValueOf(options)
+	if value.Kind() != reflect.Pointer || value.IsNil() || value.Elem().Kind() != reflect.Struct {
+		return nil, errors.New("options must be a non-nil pointer to a struct")
+	}
+
+	return collectStructFields(value.Elem(), nil)
+}
+
+func collectStructFields(value reflect.Value, fields []structField) ([]structField, error) {
+	valueType := value.Type()
+
+	for i := 0; i < valueType.NumField(); i++ {
+		field := valueType.Field(i)
+		fieldValue := value.Field(i)
+
+		_, isFlag := field.Tag.Lookup(tagFlag)
+		_, isArg := field.Tag.Lookup(tagArg)
+
+		if !isFlag && !isArg {
+			// Recurse into embedded structs, for shared groups of options
+			if field.Anonymous && fieldValue.Kind() == reflect.Struct {
+				var err error
+				if fields, err = collectStructFields(fieldValue, fields); err != nil {
+					return nil, err
+				}
+			}
+
+			continue
+		}
+
+		// Skip fields that are explicitly not flags, unless they're arguments
+		if field.Tag.Get(tagFlag) == "-" && !isArg {
+			continue
+		}
+
+		if !fieldValue.CanSet() {
+			return nil, fmt.Errorf("field '%s' must be exported", field.Name)
+		}
+
+		fields = append(fields, structField{name: field.Name, value: fieldValue, tag: field.Tag})
+	}
+
+	return fields, nil
+}
+
+// defineStructFlag defines a flag for the given field in the given flags.
+func defineStructFlag(flags *flag.FlagSet, field structField) error {
+	name := field.tag.Get(tagFlag)
+	usage := field.tag.Get(tagUsage)
+
+	if name == "" {
+		return fmt.Errorf("field '%s' must have a flag name", field.name)
+	}
+
+	if flags.Lookup(name) != nil {
+		return fmt.Errorf("flag '%s' is declared more than once", name)
+	}
+
+	switch p := field.value.Addr().Interface().(type) {
+	case flag.Value:
+		flags.Var(p, name, usage)
+	case *time.Duration:
+		flags.DurationVar(p, name, *p, usage)
+	case encoding.TextUnmarshaler:
+		flags.Var(&textValue{value: field.value}, name, usage)
+	default:
+		if err := defineKindFlag(flags, field.value, name, usage); err != nil {
+			return fmt.Errorf("field '%s': %w", field.name, err)
+		}
+	}
+
+	f := flags.Lookup(name)
+
+	if def, hasDefault := field.tag.Lookup(tagDefault); hasDefault {
+		// Set the value directly (rather than via the flags), so that the flag
+		// isn't considered to have been set
+		if err := f.Value.Set(def); err != nil {
+			return fmt.Errorf("invalid default %q for flag '%s': %v", def, name, err)
+		}
+
+		f.DefValue = f.Value.String()
+
+		// Let the first parsed value replace the default, rather than append
+		switch value := f.Value.(type) {
+		case *sliceValue:
+			value.changed = false
+		case *stringMapValue:
+			value.changed = false
+		}
+	}
+
+	if shorthand := field.tag.Get(tagShort); shorthand != "" {
+		if err := SetFlagShorthand(flags, name, shorthand); err != nil {
+			return err
+		}
+	}
+
+	if env := field.tag.Get(tagEnv); env != "" {
+		if err := SetFlagEnv(flags, name, env); err != nil {
+			return err
+		}
+	}
+
+	negatable, err := isTrueTag(field, tagNegatable)
+	if err != nil {
+		return err
+	}
+
+	if negatable {
+		if err := MarkFlagNegatable(flags, name); err != nil {
+			return err
+		}
+	}
+
+	required, err := isTrueTag(field, tagRequired)
+	if err != nil {
+		return err
+	}
+
+	if required {
+		return MarkFlagRequired(flags, name)
+	}
+
+	return nil
+}
+
+// defineKindFlag defines a flag for the given value based on its kind, so that
+// named types (like `type Level string`) are supported.
+func defineKindFlag(flags *flag.FlagSet, value reflect.Value, name string, usage string) error {
+	switch value.Kind() {
+	case reflect.String:
+		p := fieldPointer[string](value)
+		flags.StringVar(p, name, *p, usage)
+	case reflect.Bool:
+		p := fieldPointer[bool](value)
+		flags.BoolVar(p, name, *p, usage)
+	case reflect.Int:
+		p := fieldPointer[int](value)
+		flags.IntVar(p, name, *p, usage)
+	case reflect.Int64:
+		p := fieldPointer[int64](value)
+		flags.Int64Var(p, name, *p, usage)
+	case reflect.Uint:
+		p := fieldPointer[uint](value)
+		flags.UintVar(p, name, *p, usage)
+	case reflect.Uint64:
+		p := fieldPointer[uint64](value)
+		flags.Uint64Var(p, name, *p, usage)
+	case reflect.Float64:
+		p := fieldPointer[float64](value)
+		flags.Float64Var(p, name, *p, usage)
+	case reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Float32:
+		flags.Var(&sizedNumberValue{textValue{value: value}}, name, usage)
+	case reflect.Slice:
+		if !isScalarType(value.Type().Elem()) {
+			return fmt.Errorf("unsupported flag type '%s'", value.Type())
+		}
+
+		flags.Var(&sliceValue{value: value}, name, usage)
+	case reflect.Map:
+		if value.Type().Key().Kind() != reflect.String || value.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported flag type '%s'", value.Type())
+		}
+
+		flags.Var(StringMapValue(fieldPointer[map[string]string](value)), name, usage)
+	default:
+		return fmt.Errorf("unsupported flag type '%s'", value.Type())
+	}
+
+	return nil
+}
+
+// fieldPointer returns a pointer to the given addressable value, converted to a
+// pointer of the given type.
+func fieldPointer[T any](value reflect.Value) *T {
+	return value.Addr().Convert(reflect.TypeOf((*T)(nil))).Interface().(*T)
+}
+
+// isTrueTag returns whether the given field has the given boolean tag set to a
+// true value.
+func isTrueTag(field structField, key string) (bool, error) {
+	value, hasTag := field.tag.Lookup(key)
+	if !hasTag {
+		return false, nil
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s tag %q for field '%s'", key, value, field.name)
+	}
+
+	return b, nil
+}
+
+// isSliceField returns whether the given value is a slice of multiple values,
+// rather than a single value that happens to be a slice (like net.IP).
+func isSliceField(value reflect.Value) bool {
+	if _, ok := value.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return false
+	}
+
+	return value.Kind() == reflect.Slice
+}
+
+// isScalarType returns whether a value of the given type can be set from a
+// single string via setScalar.
+func isScalarType(t reflect.Type) bool {
+	if reflect.PointerTo(t).Implements(reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()) {
+		return true
+	}
+
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+
+	return false
+}
+
+// setScalar parses the given string into the given addressable value.
+func setScalar(value reflect.Value, s string) error {
+	if unmarshaler, ok := value.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return unmarshaler.UnmarshalText([]byte(s))
+	}
+
+	if value.Type() == durationType {
+		duration, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+
+		value.SetInt(int64(duration))
+		return nil
+	}
+
+	switch value.Kind() {
+	case reflect.String:
+		value.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return strconv.ErrSyntax
+		}
+
+		value.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 0, value.Type().Bits())
+		if err != nil {
+			return numError(err)
+		}
+
+		value.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(s, 0, value.Type().Bits())
+		if err != nil {
+			return numError(err)
+		}
+
+		value.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, value.Type().Bits())
+		if err != nil {
+			return numError(err)
+		}
+
+		value.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported type '%s'", value.Type())
+	}
+
+	return nil
+}
+
+// numError returns the underlying error of the given error from parsing a
+// number, such as strconv.ErrRange, without the repetition of the input.
+func numError(err error) error {
+	var numErr *strconv.NumError
+	if errors.As(err, &numErr) {
+		return numErr.Err
+	}
+
+	return err
+}
+
+// formatScalar formats the given value as a string that setScalar can parse.
+func formatScalar(value reflect.Value) string {
+	if value.CanAddr() {
+		if marshaler, ok := value.Addr().Interface().(encoding.TextMarshaler); ok {
+			text, err := marshaler.MarshalText()
+			if err == nil {
+				return string(text)
+			}
+		}
+	}
+
+	return fmt.Sprint(value.Interface())
+}
+
+// textValue is a flag value for a field that implements
+// encoding.TextUnmarshaler.
+type textValue struct {
+	value reflect.Value
+}
+
+func (v *textValue) String() string {
+	// Guard against the zero value that the flag package creates when
+	// determining a flag's zero value
+	if !v.value.IsValid() {
+		return ""
+	}
+
+	return formatScalar(v.value)
+}
+
+func (v *textValue) Set(s string) error {
+	return setScalar(v.value, s)
+}
+
+func (v *textValue) Get() any {
+	return v.value.Interface()
+}
+
+// sizedNumberValue is a flag value for a numeric field of a specific size (like
+// uint16), which the flag package doesn't support directly.
+type sizedNumberValue struct {
+	textValue
+}
+
+func (v *sizedNumberValue) Type() string {
+	if !v.value.IsValid() {
+		return "value"
+	}
+
+	return v.value.Kind().String()
+}
+
+// sliceValue is a flag value for a slice field, which may be set multiple
+// times or with comma separated values.
+type sliceValue struct {
+	value reflect.Value
+
+	changed bool // Whether the slice has been set, rather than holding a default
+}
+
+func (v *sliceValue) String() string {
+	if !v.value.IsValid() {
+		return ""
+	}
+
+	values := make([]string, v.value.Len())
+	for i := range values {
+		values[i] = formatScalar(v.value.Index(i))
+	}
+
+	return strings.Join(values, ",")
+}
+
+func (v *sliceValue) Set(s string) error {
+	values := v.value
+	if !v.changed {
+		values = reflect.MakeSlice(v.value.Type(), 0, 1)
+	}
+
+	for _, part := range strings.Split(s, ",") {
+		elem := reflect.New(v.value.Type().Elem()).Elem()
+		if err := setScalar(elem, part); err != nil {
+			return err
+		}
+
+		values = reflect.Append(values, elem)
+	}
+
+	v.value.Set(values)
+	v.changed = true
+
+	return nil
+}
+
+// reset treats the slice as a default again, so that it's replaced when the
+// flag is first set in a run.
+func (v *sliceValue) reset() {
+	v.changed = false
+}
+
+func (v *sliceValue) Get() any {
+	return v.value.Interface()
+}
+
+func (v *sliceValue) Type() string {
+	if !v.value.IsValid() {
+		return "values"
+	}
+
+	elemType := v.value.Type().Elem()
+
+	switch {
+	case elemType == durationType:
+		return "durations"
+	case elemType.Kind() == reflect.Struct:
+		return "values"
+	default:
+		return elemType.Kind().String() + "s"
+	}
+}
@@ -0,0 +1,397 @@
+package lieut
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testLevel string
+
+type testUpperText string
+
+func (u *testUpperText) UnmarshalText(text []byte) error {
+	*u = testUpperText(strings.ToUpper(string(text)))
+	return nil
+}
+
+func (u testUpperText) MarshalText() ([]byte, error) {
+	return []byte(u), nil
+}
+
+type testSharedOptions struct {
+	Verbose bool `flag:"verbose" short:"v" usage:"Enable verbose output"`
+}
+
+type testStructOptions struct {
+	testSharedOptions
+
+	Timezone string        `flag:"timezone" short:"t" usage:"The timezone" env:"TZ" default:"UTC"`
+	Count    int           `flag:"count" usage:"The count" required:"true"`
+	Size     uint64        `flag:"size"`
+	Ratio    float64       `flag:"ratio"`
+	Timeout  time.Duration `flag:"timeout" default:"5s"`
+	Level    testLevel     `flag:"level"`
+	Tags     []string      `flag:"tag" default:"a,b"`
+	Ports    []int         `flag:"port"`
+	Name     testUpperText `flag:"name"`
+
+	Ignored string `flag:"-"`
+	Plain   string
+
+	Source string   `flag:"-" arg:"source" required:"true"`
+	Files  []string `arg:"files"`
+}
+
+func TestStructFlags(t *testing.T) {
+	options := testStructOptions{Level: "info"}
+
+	flags, err := StructFlags("test", &options)
+	if err != nil {
+		t.Fatalf("StructFlags returned error: %v", err)
+	}
+
+	var names []string
+	visitFlags(flags, func(f flagInfo) {
+		names = append(names, f.name)
+	})
+
+	wantNames := []string{"count", "level", "name", "port", "ratio", "size", "tag", "timeout", "timezone", "verbose"}
+	if !reflect.DeepEqual(names, wantNames) {
+		t.Errorf("StructFlags defined flags %q, want %q", names, wantNames)
+	}
+
+	wantTags := []string{"a", "b"}
+	if options.Timezone != "UTC" || options.Timeout != 5*time.Second || !reflect.DeepEqual(options.Tags, wantTags) {
+		t.Errorf("StructFlags didn't apply defaults, got %+v", options)
+	}
+
+	if f := flags.Lookup("timezone"); f.DefValue != "UTC" {
+		t.Errorf("StructFlags gave timezone default %q, want %q", f.DefValue, "UTC")
+	}
+
+	var set []string
+	visitSetFlags(flags, func(f flagInfo) {
+		set = append(set, f.name)
+	})
+
+	if len(set) != 0 {
+		t.Errorf("StructFlags set flags %q, want none", set)
+	}
+
+	err = flags.Parse([]string{
+		"-v",
+		"-t", "America/New_York",
+		"-count", "3",
+		"-size", "1024",
+		"-ratio", "0.5",
+		"-timeout", "1m",
+		"-level", "debug",
+		"-tag", "c",
+		"-tag", "d,e",
+		"-port", "80,443",
+		"-name", "lieut",
+	})
+	if err != nil {
+		t.Fatalf("flags.Parse returned error: %v", err)
+	}
+
+	want := testStructOptions{
+		testSharedOptions: testSharedOptions{Verbose: true},
+
+		Timezone: "America/New_York",
+		Count:    3,
+		Size:     1024,
+		Ratio:    0.5,
+		Timeout:  time.Minute,
+		Level:    "debug",
+		Tags:     []string{"c", "d", "e"},
+		Ports:    []int{80, 443},
+		Name:     "LIEUT",
+	}
+
+	if !reflect.DeepEqual(options, want) {
+		t.Errorf("flags.Parse gave options %+v, want %+v", options, want)
+	}
+}
+
+func TestStructFlags_SizedNumbers(t *testing.T) {
+	type sizedOptions struct {
+		Port   uint16   `flag:"port" default:"8080"`
+		Offset int8     `flag:"offset"`
+		Weight float32  `flag:"weight"`
+		Ports  []uint16 `flag:"ports"`
+	}
+
+	for testName, testData := range map[string]struct {
+		arguments []string
+
+		want    sizedOptions
+		wantErr bool
+	}{
+		"defaults": {
+			want: sizedOptions{Port: 8080},
+		},
+		"set": {
+			arguments: []string{"-port", "443", "-offset", "-5", "-weight", "0.5", "-ports", "80,443"},
+
+			want: sizedOptions{Port: 443, Offset: -5, Weight: 0.5, Ports: []uint16{80, 443}},
+		},
+		"out of range": {
+			arguments: []string{"-port", "70000"},
+
+			wantErr: true,
+		},
+		"out of range slice": {
+			arguments: []string{"-ports", "70000"},
+
+			wantErr: true,
+		},
+	} {
+		t.Run(testName, func(t *testing.T) {
+			var options sizedOptions
+
+			flags, err := StructFlags("test", &options)
+			if err != nil {
+				t.Fatalf("StructFlags returned error: %v", err)
+			}
+
+			flags.SetOutput(io.Discard)
+
+			err = flags.Parse(testData.arguments)
+			if (err != nil) != testData.wantErr {
+				t.Fatalf("flags.Parse returned error %v, want error %t", err, testData.wantErr)
+			}
+
+			if !testData.wantErr && !reflect.DeepEqual(options, testData.want) {
+				t.Errorf("flags.Parse gave options %+v, want %+v", options, testData.want)
+			}
+		})
+	}
+}
+
+func TestStructFlags_Annotations(t *testing.T) {
+	flags, err := StructFlags("test", &testStructOptions{})
+	if err != nil {
+		t.Fatalf("StructFlags returned error: %v", err)
+	}
+
+	infos := make(map[string]flagInfo)
+	visitFlags(flags, func(f flagInfo) {
+		infos[f.name] = f
+	})
+
+	if f := infos["timezone"]; f.shorthand != "t" || f.env != "TZ" || f.usage != "The timezone" {
+		t.Errorf("StructFlags gave timezone info %+v", f)
+	}
+
+	if f := infos["count"]; !f.required {
+		t.Errorf("StructFlags gave count info %+v, want required", f)
+	}
+
+	if f := infos["verbose"]; f.shorthand != "v" {
+		t.Errorf("StructFlags gave verbose info %+v, want shorthand %q", f, "v")
+	}
+}
+
+func TestStructFlags_Negatable(t *testing.T) {
+	options := struct {
+		Color bool `flag:"color" default:"true" negatable:"true"`
+	}{}
+
+	flags, err := StructFlags("test", &options)
+	if err != nil {
+		t.Fatalf("StructFlags returned error: %v", err)
+	}
+
+	if err := flags.Parse([]string{"-no-color"}); err != nil {
+		t.Fatalf("flags.Parse returned error: %v", err)
+	}
+
+	if options.Color {
+		t.Error("negated form didn't set the field to false")
+	}
+}
+
+func TestStructFlags_Errors(t *testing.T) {
+	for testName, testData := range map[string]struct {
+		options any
+		wantErr string
+	}{
+		"nil options": {
+			options: nil,
+			wantErr: "options must be a non-nil pointer to a struct",
+		},
+		"non-pointer options": {
+			options: testStructOptions{},
+			wantErr: "options must be a non-nil pointer to a struct",
+		},
+		"unsupported type": {
+			options: &struct {
+				Channel chan int `flag:"channel"`
+			}{},
+			wantErr: "field 'Channel': unsupported flag type 'chan int'",
+		},
+		"unsupported slice type": {
+			options: &struct {
+				Maps []map[string]string `flag:"maps"`
+			}{},
+			wantErr: "field 'Maps': unsupported flag type '[]map[string]string'",
+		},
+		"missing name": {
+			options: &struct {
+				Name string `flag:""`
+			}{},
+			wantErr: "field 'Name' must have a flag name",
+		},
+		"duplicate name": {
+			options: &struct {
+				Name  string `flag:"name"`
+				Other string `flag:"name"`
+			}{},
+			wantErr: "flag 'name' is declared more than once",
+		},
+		"invalid default": {
+			options: &struct {
+				Count int `flag:"count" default:"many"`
+			}{},
+			wantErr: `invalid default "many" for flag 'count': parse error`,
+		},
+		"invalid required": {
+			options: &struct {
+				Count int `flag:"count" required:"maybe"`
+			}{},
+			wantErr: `invalid required tag "maybe" for field 'Count'`,
+		},
+		"invalid shorthand": {
+			options: &struct {
+				Count int `flag:"count" short:"cn"`
+			}{},
+			wantErr: "shorthand 'cn' must be a single character",
+		},
+		"unexported field": {
+			options: &struct {
+				count int `flag:"count"`
+			}{},
+			wantErr: "field 'count' must be exported",
+		},
+	} {
+		t.Run(testName, func(t *testing.T) {
+			_, err := StructFlags("test", testData.options)
+
+			if err == nil || err.Error() != testData.wantErr {
+				t.Errorf("StructFlags gave error %v, want %q", err, testData.wantErr)
+			}
+		})
+	}
+}
+
+func TestBindArguments(t *testing.T) {
+	type singleOptions struct {
+		Name  string `arg:"name"`
+		Count int    `arg:"count"`
+	}
+
+	for testName, testData := range map[string]struct {
+		options     any
+		arguments   []string
+		want        any
+		wantErr     string
+		wantHelpErr bool
+	}{
+		"required and rest": {
+			options:   &testStructOptions{},
+			arguments: []string{"src", "a.txt", "b.txt"},
+			want:      &testStructOptions{Source: "src", Files: []string{"a.txt", "b.txt"}},
+		},
+		"required without rest": {
+			options:   &testStructOptions{},
+			arguments: []string{"src"},
+			want:      &testStructOptions{Source: "src", Files: []string{}},
+		},
+		"missing required": {
+			options:     &testStructOptions{},
+			arguments:   nil,
+			wantErr:     "missing required argument 'source'",
+			wantHelpErr: true,
+		},
+		"optional arguments": {
+			options:   &singleOptions{},
+			arguments: []string{"lieut"},
+			want:      &singleOptions{Name: "lieut"},
+		},
+		"typed arguments": {
+			options:   &singleOptions{},
+			arguments: []string{"lieut", "3"},
+			want:      &singleOptions{Name: "lieut", Count: 3},
+		},
+		"invalid argument": {
+			options:     &singleOptions{},
+			arguments:   []string{"lieut", "three"},
+			wantErr:     `invalid value "three" for argument 'count': invalid syntax`,
+			wantHelpErr: true,
+		},
+		"out of range argument": {
+			options:     &singleOptions{},
+			arguments:   []string{"lieut", "99999999999999999999"},
+			wantErr:     `invalid value "99999999999999999999" for argument 'count': value out of range`,
+			wantHelpErr: true,
+		},
+		"arguments that aren't flags": {
+			options: &struct {
+				Name    string `flag:"-" arg:"name"`
+				Ignored string `flag:"-"`
+			}{},
+			arguments: []string{"lieut"},
+			want: &struct {
+				Name    string `flag:"-" arg:"name"`
+				Ignored string `flag:"-"`
+			}{Name: "lieut"},
+		},
+		"unexpected argument": {
+			options:     &singleOptions{},
+			arguments:   []string{"lieut", "3", "extra"},
+			wantErr:     "unexpected argument 'extra'",
+			wantHelpErr: true,
+		},
+		"slice not last": {
+			options: &struct {
+				Files []string `arg:"files"`
+				Name  string   `arg:"name"`
+			}{},
+			arguments: []string{"a"},
+			wantErr:   "argument field 'Files' must be the last argument field",
+		},
+		"invalid options": {
+			options:   singleOptions{},
+			arguments: []string{"a"},
+			wantErr:   "options must be a non-nil pointer to a struct",
+		},
+	} {
+		t.Run(testName, func(t *testing.T) {
+			err := BindArguments(testData.options, testData.arguments)
+
+			if testData.wantErr == "" {
+				if err != nil {
+					t.Fatalf("BindArguments returned error: %v", err)
+				}
+
+				if !reflect.DeepEqual(testData.options, testData.want) {
+					t.Errorf("BindArguments gave %+v, want %+v", testData.options, testData.want)
+				}
+				return
+			}
+
+			if err == nil || err.Error() != testData.wantErr {
+				t.Errorf("BindArguments gave error %v, want %q", err, testData.wantErr)
+			}
+
+			if errors.Is(err, ErrHelpRequested) != testData.wantHelpErr {
+				t.Errorf("BindArguments gave help requested %v, want %v", !testData.wantHelpErr, testData.wantHelpErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright © 2026 Trevor N. Suarez (Rican7)
+
+package lieut
+
+import (
+	"context"
+)
+
+// TypedExecutor is a functional interface that defines an executable command
+// with typed options.
+//
+// It takes a context, the command's options, and arguments, and returns an
+// error (if any occurred).
+type TypedExecutor[O any] func(ctx context.Context, options *O, arguments []string) error
+
+// SetTypedCommand sets a command on the given app for the given info and typed
+// executor.
+//
+// The command's flags are declared by the tagged fields of the options struct
+// type, as described by StructFlags. A new options struct is allocated (and its
+// flags defined) for each run, so that the executor doesn't rely on any shared
+// mutable state. If the options struct declares any argument fields, then the
+// arguments are bound to them via BindArguments before the executor is called.
+//
+// It returns an error if the options struct can't be used to declare flags, or
+// for any of the reasons that SetCommandFunc does.
+func SetTypedCommand[O any](app *MultiCommandApp, info CommandInfo, exec TypedExecutor[O]) error {
+	return app.SetCommandFunc(info, func() (Flags, Executor, error) {
+		options := new(O)
+
+		flags, err := StructFlags(info.Name, options)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		bindArguments, err := hasArgumentFields(options)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		executor := func(ctx context.Context, arguments []string) error {
+			if bindArguments {
+				if err := BindArguments(options, arguments); err != nil {
+					return err
+				}
+			}
+
+			return exec(ctx, options, arguments)
+		}
+
+		return flags, executor, nil
+	})
+}
@@ -0,0 +1,109 @@
+package lieut
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"io"
+	"reflect"
+	"testing"
+)
+
+type testTypedOptions struct {
+	Force bool     `flag:"force" usage:"Force it"`
+	Tags  []string `flag:"tag" default:"default"`
+
+	Target string `arg:"target" required:"true"`
+}
+
+func TestSetTypedCommand(t *testing.T) {
+	globalFlags := flag.NewFlagSet(testAppInfo.Name, flag.ContinueOnError)
+	verbose := globalFlags.Bool("verbose", false, "Enable verbose output")
+
+	app := NewMultiCommandApp(testAppInfo, globalFlags, io.Discard, io.Discard)
+
+	var captured []testTypedOptions
+	var capturedArgs [][]string
+
+	deploy := func(ctx context.Context, options *testTypedOptions, arguments []string) error {
+		captured = append(captured, *options)
+		capturedArgs = append(capturedArgs, arguments)
+		return nil
+	}
+
+	err := SetTypedCommand(app, CommandInfo{Name: "deploy"}, deploy)
+	if err != nil {
+		t.Fatalf("SetTypedCommand returned error: %v", err)
+	}
+
+	arguments := []string{"deploy", "-force", "-tag", "a", "-verbose", "prod"}
+	if exitCode := app.Run(context.TODO(), arguments); exitCode != ExitCodeSuccess {
+		t.Errorf("app.Run gave %v, wanted %v", exitCode, ExitCodeSuccess)
+	}
+
+	if exitCode := app.Run(context.TODO(), []string{"deploy", "staging"}); exitCode != ExitCodeSuccess {
+		t.Errorf("app.Run gave %v, wanted %v", exitCode, ExitCodeSuccess)
+	}
+
+	want := []testTypedOptions{
+		{Force: true, Tags: []string{"a"}, Target: "prod"},
+		{Force: false, Tags: []string{"default"}, Target: "staging"},
+	}
+
+	if !reflect.DeepEqual(captured, want) {
+		t.Errorf("app.Run gave options %+v, wanted %+v", captured, want)
+	}
+
+	wantArgs := [][]string{{"prod"}, {"staging"}}
+	if !reflect.DeepEqual(capturedArgs, wantArgs) {
+		t.Errorf("app.Run gave arguments %q, wanted %q", capturedArgs, wantArgs)
+	}
+
+	if !*verbose {
+		t.Error("app.Run didn't set the global flag")
+	}
+}
+
+func TestSetTypedCommand_UsageErrors(t *testing.T) {
+	var errOut bytes.Buffer
+
+	app := NewMultiCommandApp(testAppInfo, nil, io.Discard, &errOut)
+
+	executor := func(ctx context.Context, options *testTypedOptions, arguments []string) error {
+		return nil
+	}
+
+	if err := SetTypedCommand(app, CommandInfo{Name: "deploy", Usage: "<target>"}, executor); err != nil {
+		t.Fatalf("SetTypedCommand returned error: %v", err)
+	}
+
+	if exitCode := app.Run(context.TODO(), []string{"deploy"}); exitCode != ExitCodeUsageError {
+		t.Errorf("app.Run gave %v, wanted %v", exitCode, ExitCodeUsageError)
+	}
+
+	wantPrefix := "Error: missing required argument 'target'\n\nUsage: test deploy <target>\n"
+	if got := errOut.String(); !bytes.HasPrefix([]byte(got), []byte(wantPrefix)) {
+		t.Errorf("app.Run gave errOut %q, wanted prefix %q", got, wantPrefix)
+	}
+}
+
+func TestSetTypedCommand_InvalidOptions(t *testing.T) {
+	app := NewMultiCommandApp(testAppInfo, nil, io.Discard, io.Discard)
+
+	type invalidOptions struct {
+		Channel chan int `flag:"channel"`
+	}
+
+	invalid := func(ctx context.Context, options *invalidOptions, arguments []string) error {
+		return nil
+	}
+
+	err := SetTypedCommand(app, CommandInfo{Name: "invalid"}, invalid)
+	if err == nil {
+		t.Error("SetTypedCommand returned nil error for invalid options")
+	}
+
+	if names := app.CommandNames(); len(names) != 0 {
+		t.Errorf("SetTypedCommand set commands %q, wanted none", names)
+	}
+}
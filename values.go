@@ -0,0 +1,387 @@
+// Copyright © 2026 Trevor N. Suarez (Rican7)
+
+package lieut
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Value is a flag value that reports the name of its type.
+//
+// It's compatible with both the standard library's flag package and other flag
+// packages (like spf13/pflag) that require values to report their type.
+type Value interface {
+	flag.Value
+
+	Type() string
+}
+
+type countValue struct {
+	p *int
+}
+
+// CountValue returns a flag value that counts the number of times that its flag
+// is provided, such as for verbosity levels (`-v -v -v`, or `-vvv` when using
+// GNU-style flags).
+//
+// Like a boolean flag, it doesn't require a value. An explicit integer value can
+// still be provided to set the count directly, such as `--verbose=2`.
+func CountValue(p *int) Value {
+	return &countValue{p: p}
+}
+
+func (v *countValue) String() string {
+	if v.p == nil {
+		return "0"
+	}
+
+	return strconv.Itoa(*v.p)
+}
+
+func (v *countValue) Set(s string) error {
+	switch s {
+	case "true":
+		*v.p++
+		return nil
+	case "false":
+		*v.p = 0
+		return nil
+	}
+
+	count, err := strconv.Atoi(s)
+	if err != nil {
+		return strconv.ErrSyntax
+	}
+
+	*v.p = count
+
+	return nil
+}
+
+func (v *countValue) Get() any {
+	return *v.p
+}
+
+func (v *countValue) IsBoolFlag() bool {
+	return true
+}
+
+func (v *countValue) Type() string {
+	return "count"
+}
+
+type stringSliceValue struct {
+	p *[]string
+
+	changed bool // Whether the slice has been set, rather than holding a default
+}
+
+// StringSliceValue returns a flag value that collects strings into a slice,
+// from either repeated flags (`--tag a --tag b`) or comma separated values
+// (`--tag a,b`).
+//
+// The slice's existing values are used as a default, which is replaced (rather
+// than appended to) when the flag is first set.
+func StringSliceValue(p *[]string) Value {
+	return &stringSliceValue{p: p}
+}
+
+func (v *stringSliceValue) String() string {
+	if v.p == nil {
+		return ""
+	}
+
+	return strings.Join(*v.p, ",")
+}
+
+func (v *stringSliceValue) Set(s string) error {
+	if !v.changed {
+		*v.p = nil
+		v.changed = true
+	}
+
+	*v.p = append(*v.p, strings.Split(s, ",")...)
+
+	return nil
+}
+
+func (v *stringSliceValue) Get() any {
+	return *v.p
+}
+
+func (v *stringSliceValue) Type() string {
+	return "strings"
+}
+
+// reset treats the slice as a default again, so that it's replaced when the
+// flag is first set in a run.
+func (v *stringSliceValue) reset() {
+	v.changed = false
+}
+
+type stringMapValue struct {
+	p *map[string]string
+
+	changed bool // Whether the map has been set, rather than holding a default
+}
+
+// StringMapValue returns a flag value that collects `key=value` pairs into a
+// map, from either repeated flags (`--env a=1 --env b=2`) or comma separated
+// pairs (`--env a=1,b=2`).
+//
+// The map's existing pairs are used as a default, which is replaced (rather
+// than merged into) when the flag is first set.
+func StringMapValue(p *map[string]string) Value {
+	return &stringMapValue{p: p}
+}
+
+func (v *stringMapValue) String() string {
+	if v.p == nil || len(*v.p) == 0 {
+		return ""
+	}
+
+	pairs := make([]string, 0, len(*v.p))
+	for key, value := range *v.p {
+		pairs = append(pairs, key+"="+value)
+	}
+
+	sort.Strings(pairs)
+
+	return strings.Join(pairs, ",")
+}
+
+func (v *stringMapValue) Set(s string) error {
+	pairs := strings.Split(s, ",")
+	parsed := make(map[string]string, len(pairs))
+
+	for _, pair := range pairs {
+		key, value, hasSeparator := strings.Cut(pair, "=")
+		if !hasSeparator || key == "" {
+			return fmt.Errorf("'%s' must be formatted as key=value", pair)
+		}
+
+		parsed[key] = value
+	}
+
+	// Replace the default with a new map, leaving the default's map untouched
+	if !v.changed || *v.p == nil {
+		*v.p = make(map[string]string, len(parsed))
+		v.changed = true
+	}
+
+	for key, value := range parsed {
+		(*v.p)[key] = value
+	}
+
+	return nil
+}
+
+func (v *stringMapValue) Get() any {
+	return *v.p
+}
+
+func (v *stringMapValue) Type() string {
+	return "key=value"
+}
+
+// reset treats the map as a default again, so that it's replaced when the flag
+// is first set in a run.
+func (v *stringMapValue) reset() {
+	v.changed = false
+}
+
+type enumValue struct {
+	p       *string
+	choices []string
+}
+
+// EnumValue returns a flag value that only accepts one of the given choices.
+//
+// The choices are listed alongside the flag's usage in help output, and setting
+// any other value results in an error that lists the choices.
+func EnumValue(p *string, choices ...string) Value {
+	return &enumValue{p: p, choices: choices}
+}
+
+func (v *enumValue) String() string {
+	if v.p == nil {
+		return ""
+	}
+
+	return *v.p
+}
+
+func (v *enumValue) Set(s string) error {
+	for _, choice := range v.choices {
+		if s == choice {
+			*v.p = s
+			return nil
+		}
+	}
+
+	return fmt.Errorf("must be one of: %s", strings.Join(v.choices, ", "))
+}
+
+func (v *enumValue) Get() any {
+	return *v.p
+}
+
+func (v *enumValue) Type() string {
+	return "string"
+}
+
+// Choices returns the allowed choices of the value.
+func (v *enumValue) Choices() []string {
+	return append([]string(nil), v.choices...)
+}
+
+// byteSizeUnits are the units of byte sizes, in order of preference when
+// formatting a size.
+var byteSizeUnits = []struct {
+	name string
+	size uint64
+}{
+	{"PiB", 1 << 50},
+	{"TiB", 1 << 40},
+	{"GiB", 1 << 30},
+	{"MiB", 1 << 20},
+	{"KiB", 1 << 10},
+	{"PB", 1e15},
+	{"TB", 1e12},
+	{"GB", 1e9},
+	{"MB", 1e6},
+	{"kB", 1e3},
+	{"B", 1},
+}
+
+type byteSizeValue struct {
+	p *uint64
+}
+
+// ByteSizeValue returns a flag value that parses a size in bytes, such as
+// `512`, `10MiB`, or `1.5GB`.
+//
+// Both binary (KiB, MiB, GiB, TiB, PiB) and decimal (kB, MB, GB, TB, PB) units
+// are supported, case-insensitively. A size without a unit is in bytes.
+func ByteSizeValue(p *uint64) Value {
+	return &byteSizeValue{p: p}
+}
+
+func (v *byteSizeValue) String() string {
+	if v.p == nil || *v.p == 0 {
+		return "0"
+	}
+
+	for _, unit := range byteSizeUnits {
+		if *v.p%unit.size == 0 {
+			return strconv.FormatUint(*v.p/unit.size, 10) + unit.name
+		}
+	}
+
+	return strconv.FormatUint(*v.p, 10)
+}
+
+func (v *byteSizeValue) Set(s string) error {
+	number := strings.TrimRightFunc(s, unicode.IsLetter)
+	unitName := strings.TrimSpace(s[len(number):])
+	number = strings.TrimSpace(number)
+
+	multiplier := uint64(1)
+	if unitName != "" {
+		found := false
+		for _, unit := range byteSizeUnits {
+			if strings.EqualFold(unitName, unit.name) {
+				multiplier, found = unit.size, true
+				break
+			}
+		}
+
+		if !found {
+			return fmt.Errorf("unknown size unit '%s'", unitName)
+		}
+	}
+
+	if size, err := strconv.ParseUint(number, 10, 64); err == nil {
+		if size > math.MaxUint64/multiplier {
+			return strconv.ErrRange
+		}
+
+		*v.p = size * multiplier
+		return nil
+	}
+
+	size, err := strconv.ParseFloat(number, 64)
+	if err != nil || size < 0 {
+		return errors.New("must be a size, such as 512, 10MiB, or 1.5GB")
+	}
+
+	size *= float64(multiplier)
+	if size >= math.MaxUint64 {
+		return strconv.ErrRange
+	}
+
+	*v.p = uint64(size)
+
+	return nil
+}
+
+func (v *byteSizeValue) Get() any {
+	return *v.p
+}
+
+func (v *byteSizeValue) Type() string {
+	return "size"
+}
+
+type timestampValue struct {
+	p       *time.Time
+	layouts []string
+}
+
+// TimestampValue returns a flag value that parses a timestamp in any of the
+// given layouts (as understood by time.Parse), or in the RFC 3339 layout if no
+// layouts are given.
+//
+// The timestamp is formatted in the first layout.
+func TimestampValue(p *time.Time, layouts ...string) Value {
+	if len(layouts) == 0 {
+		layouts = []string{time.RFC3339}
+	}
+
+	return &timestampValue{p: p, layouts: layouts}
+}
+
+func (v *timestampValue) String() string {
+	if v.p == nil || v.p.IsZero() {
+		return ""
+	}
+
+	return v.p.Format(v.layouts[0])
+}
+
+func (v *timestampValue) Set(s string) error {
+	for _, layout := range v.layouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			*v.p = t
+			return nil
+		}
+	}
+
+	return fmt.Errorf("must be a timestamp formatted as %s", strings.Join(v.layouts, " or "))
+}
+
+func (v *timestampValue) Get() any {
+	return *v.p
+}
+
+func (v *timestampValue) Type() string {
+	return "timestamp"
+}
@@ -0,0 +1,184 @@
+package lieut
+
+import (
+	"flag"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCountValue(t *testing.T) {
+	var count int
+
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags.Var(CountValue(&count), "v", "Increase verbosity")
+
+	if err := flags.Parse([]string{"-v", "-v", "-v"}); err != nil {
+		t.Fatalf("flags.Parse returned error: %v", err)
+	}
+
+	if count != 3 {
+		t.Errorf("count gave %d, want %d", count, 3)
+	}
+
+	if err := flags.Set("v", "7"); err != nil || count != 7 {
+		t.Errorf("flags.Set gave count %d with error %v, want %d", count, err, 7)
+	}
+
+	if err := flags.Set("v", "false"); err != nil || count != 0 {
+		t.Errorf("flags.Set gave count %d with error %v, want %d", count, err, 0)
+	}
+
+	if err := flags.Set("v", "lots"); err == nil {
+		t.Error("flags.Set returned nil error for an invalid count")
+	}
+}
+
+func TestStringSliceValue(t *testing.T) {
+	tags := []string{"default"}
+
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags.Var(StringSliceValue(&tags), "tag", "A tag")
+
+	if got := flags.Lookup("tag").DefValue; got != "default" {
+		t.Errorf("DefValue gave %q, want %q", got, "default")
+	}
+
+	if err := flags.Parse([]string{"-tag", "a", "-tag", "b,c"}); err != nil {
+		t.Fatalf("flags.Parse returned error: %v", err)
+	}
+
+	if want := []string{"a", "b", "c"}; !reflect.DeepEqual(tags, want) {
+		t.Errorf("tags gave %q, want %q", tags, want)
+	}
+}
+
+func TestStringMapValue(t *testing.T) {
+	defaults := map[string]string{"z": "0"}
+	env := defaults
+
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	flags.Var(StringMapValue(&env), "env", "An environment variable")
+
+	if err := flags.Parse([]string{"-env", "a=1", "-env", "b=2,c=x=y"}); err != nil {
+		t.Fatalf("flags.Parse returned error: %v", err)
+	}
+
+	if want := map[string]string{"a": "1", "b": "2", "c": "x=y"}; !reflect.DeepEqual(env, want) {
+		t.Errorf("env gave %q, want %q", env, want)
+	}
+
+	if want := map[string]string{"z": "0"}; !reflect.DeepEqual(defaults, want) {
+		t.Errorf("defaults gave %q, want %q", defaults, want)
+	}
+
+	if got, want := flags.Lookup("env").Value.String(), "a=1,b=2,c=x=y"; got != want {
+		t.Errorf("String gave %q, want %q", got, want)
+	}
+
+	for _, invalid := range []string{"a", "=1", "a=1,b"} {
+		err := flags.Set("env", invalid)
+		if err == nil || !strings.HasSuffix(err.Error(), "must be formatted as key=value") {
+			t.Errorf("flags.Set(%q) gave error %v", invalid, err)
+		}
+	}
+}
+
+func TestEnumValue(t *testing.T) {
+	level := "info"
+
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	flags.Var(EnumValue(&level, "debug", "info", "error"), "level", "The log level")
+
+	if err := flags.Parse([]string{"-level", "debug"}); err != nil {
+		t.Fatalf("flags.Parse returned error: %v", err)
+	}
+
+	if level != "debug" {
+		t.Errorf("level gave %q, want %q", level, "debug")
+	}
+
+	err := flags.Parse([]string{"-level", "verbose"})
+	want := `invalid value "verbose" for flag -level: must be one of: debug, info, error`
+
+	if err == nil || err.Error() != want {
+		t.Errorf("flags.Parse gave error %v, want %q", err, want)
+	}
+}
+
+func TestByteSizeValue(t *testing.T) {
+	for input, want := range map[string]uint64{
+		"0":      0,
+		"512":    512,
+		"512B":   512,
+		"1kB":    1000,
+		"1KiB":   1024,
+		"10MiB":  10 << 20,
+		"10mib":  10 << 20,
+		"1.5GB":  1500000000,
+		"1.5GiB": 3 << 29,
+		"2 TiB":  2 << 40,
+		"1PB":    1e15,
+	} {
+		var size uint64
+		if err := ByteSizeValue(&size).Set(input); err != nil || size != want {
+			t.Errorf("Set(%q) gave %d with error %v, want %d", input, size, err, want)
+		}
+	}
+
+	for _, invalid := range []string{"", "MiB", "-1", "10XB", "1.2.3GB", "20000PiB"} {
+		var size uint64
+		if err := ByteSizeValue(&size).Set(invalid); err == nil {
+			t.Errorf("Set(%q) returned nil error", invalid)
+		}
+	}
+
+	for size, want := range map[uint64]string{
+		0:          "0",
+		512:        "512B",
+		1024:       "1KiB",
+		10 << 20:   "10MiB",
+		1500000000: "1500MB",
+		1023:       "1023B",
+	} {
+		size := size
+		if got := ByteSizeValue(&size).String(); got != want {
+			t.Errorf("String gave %q for %d, want %q", got, size, want)
+		}
+	}
+}
+
+func TestTimestampValue(t *testing.T) {
+	var timestamp time.Time
+
+	value := TimestampValue(&timestamp, "2006-01-02", time.RFC3339)
+
+	if err := value.Set("2026-10-18"); err != nil {
+		t.Fatalf("Set returned error: %v", err)
+	}
+
+	if want := time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC); !timestamp.Equal(want) {
+		t.Errorf("timestamp gave %v, want %v", timestamp, want)
+	}
+
+	if err := value.Set("2026-10-18T12:30:00Z"); err != nil || timestamp.Hour() != 12 {
+		t.Errorf("Set gave %v with error %v", timestamp, err)
+	}
+
+	if got := value.String(); got != "2026-10-18" {
+		t.Errorf("String gave %q, want %q", got, "2026-10-18")
+	}
+
+	want := "must be a timestamp formatted as 2006-01-02 or 2006-01-02T15:04:05Z07:00"
+	if err := value.Set("yesterday"); err == nil || err.Error() != want {
+		t.Errorf("Set gave error %v, want %q", err, want)
+	}
+
+	if got := TimestampValue(new(time.Time)).String(); got != "" {
+		t.Errorf("String gave %q for zero time, want empty", got)
+	}
+}